@@ -0,0 +1,27 @@
+package bets
+
+import (
+	"net/http"
+)
+
+type BetError struct {
+	Code    string
+	Message string
+	Status  int
+}
+
+func (e *BetError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+var (
+	ErrInvalidRequest     = &BetError{Code: "INVALID_REQUEST", Message: "event_code, price_code and coefficient are required", Status: http.StatusBadRequest}
+	ErrInvalidStake       = &BetError{Code: "INVALID_STAKE", Message: "stake must be positive", Status: http.StatusBadRequest}
+	ErrEventNotFound      = &BetError{Code: "EVENT_NOT_FOUND", Message: "event is not running", Status: http.StatusNotFound}
+	ErrPriceNotFound      = &BetError{Code: "PRICE_NOT_FOUND", Message: "price is not offered for this event", Status: http.StatusNotFound}
+	ErrPriceClosed        = &BetError{Code: "PRICE_CLOSED", Message: "price is no longer active", Status: http.StatusConflict}
+	ErrPriceSuspended     = &BetError{Code: "PRICE_SUSPENDED", Message: "markets are suspended for this event", Status: http.StatusConflict}
+	ErrOddsChanged        = &BetError{Code: "ODDS_CHANGED", Message: "coefficient moved beyond the accepted tolerance", Status: http.StatusConflict}
+	ErrStakeLimitExceeded = &BetError{Code: "STAKE_LIMIT_EXCEEDED", Message: "stake is above the market limit", Status: http.StatusUnprocessableEntity}
+	ErrBetNotStored       = &BetError{Code: "BET_NOT_STORED", Message: "bet could not be stored", Status: http.StatusInternalServerError}
+)
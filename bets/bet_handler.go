@@ -0,0 +1,43 @@
+package bets
+
+import (
+	"errors"
+	"github.com/VaheMuradyan/Live2/db/models"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type BetHandler struct {
+	service *BetService
+}
+
+func NewHandler(service *BetService) *BetHandler {
+	return &BetHandler{service: service}
+}
+
+func (h *BetHandler) PlaceBet(c *gin.Context) {
+	var req models.PlaceBetRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cant bind request", "code": ErrInvalidRequest.Code})
+		return
+	}
+
+	bet, err := h.service.PlaceBet(req)
+	if err != nil {
+		var betErr *BetError
+		if !errors.As(err, &betErr) {
+			betErr = ErrBetNotStored
+		}
+		c.JSON(betErr.Status, gin.H{"error": betErr.Message, "code": betErr.Code})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "bet accepted", "data": models.PlaceBetResponse{
+		BetID:       bet.ID,
+		EventCode:   req.EventCode,
+		PriceCode:   req.PriceCode,
+		Stake:       bet.Stake,
+		Coefficient: bet.Coefficient,
+	}})
+}
@@ -0,0 +1,101 @@
+package bets
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/VaheMuradyan/Live2/cache"
+	"github.com/VaheMuradyan/Live2/db/models"
+	"github.com/VaheMuradyan/Live2/liability"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestHandler serves bets on event MA, with 1X2 priced at 2.00 and no
+// stake limit of its own, BTTS_Y at 1.80 limited to 50 and BTTS_N closed,
+// and on event MB, whose markets are suspended.
+func newTestHandler(t *testing.T) *gin.Engine {
+	t.Helper()
+	t.Setenv("BET_ODDS_TOLERANCE", "0.02")
+	t.Setenv("BET_MAX_STAKE", "100")
+
+	oneXTwo := models.Market{Code: "1X2"}
+	btts := models.Market{Code: "BTTS", MaxStake: 50}
+	eventPrice := func(id, eventID uint, price models.Price, coefficient float64, active bool) models.EventPrice {
+		price.Model = gorm.Model{ID: id}
+		return models.EventPrice{
+			Model:       gorm.Model{ID: eventID*100 + id},
+			EventID:     eventID,
+			PriceID:     id,
+			Price:       price,
+			Coefficient: coefficient,
+			Active:      active,
+		}
+	}
+
+	priceCache := cache.NewCacheWithStore(nil, cache.NewMemoryPriceStore())
+	priceCache.LoadEvents(
+		[]models.Event{
+			{Model: gorm.Model{ID: 1}, Code: "MA"},
+			{Model: gorm.Model{ID: 2}, Code: "MB"},
+		},
+		[]models.EventPrice{
+			eventPrice(1, 1, models.Price{Code: "1", Market: oneXTwo}, 2, true),
+			eventPrice(2, 1, models.Price{Code: "BTTS_Y", Market: btts}, 1.8, true),
+			eventPrice(3, 1, models.Price{Code: "BTTS_N", Market: btts}, 1.9, false),
+			eventPrice(1, 2, models.Price{Code: "1", Market: oneXTwo}, 2, true),
+		},
+	)
+	priceCache.SuspendEvent(2, models.IncidentGoal, time.Now(), time.Minute)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/bets", NewHandler(NewBetService(nil, priceCache, liability.NewTracker())).PlaceBet)
+	return router
+}
+
+func TestPlaceBetRejections(t *testing.T) {
+	router := newTestHandler(t)
+
+	tests := []struct {
+		name string
+		req  models.PlaceBetRequest
+		want *BetError
+	}{
+		{"missing price code", models.PlaceBetRequest{EventCode: "MA", Stake: 10, Coefficient: 2}, ErrInvalidRequest},
+		{"zero stake", models.PlaceBetRequest{EventCode: "MA", PriceCode: "1", Coefficient: 2}, ErrInvalidStake},
+		{"unknown event", models.PlaceBetRequest{EventCode: "ZZ", PriceCode: "1", Stake: 10, Coefficient: 2}, ErrEventNotFound},
+		{"unknown price", models.PlaceBetRequest{EventCode: "MA", PriceCode: "X", Stake: 10, Coefficient: 2}, ErrPriceNotFound},
+		{"closed price", models.PlaceBetRequest{EventCode: "MA", PriceCode: "BTTS_N", Stake: 10, Coefficient: 1.9}, ErrPriceClosed},
+		{"suspended event", models.PlaceBetRequest{EventCode: "MB", PriceCode: "1", Stake: 10, Coefficient: 2}, ErrPriceSuspended},
+		{"odds shortened beyond tolerance", models.PlaceBetRequest{EventCode: "MA", PriceCode: "1", Stake: 10, Coefficient: 2.1}, ErrOddsChanged},
+		{"odds drifted beyond tolerance", models.PlaceBetRequest{EventCode: "MA", PriceCode: "1", Stake: 10, Coefficient: 1.9}, ErrOddsChanged},
+		{"over BET_MAX_STAKE within tolerance", models.PlaceBetRequest{EventCode: "MA", PriceCode: "1", Stake: 100.01, Coefficient: 2.03}, ErrStakeLimitExceeded},
+		{"over market MaxStake", models.PlaceBetRequest{EventCode: "MA", PriceCode: "BTTS_Y", Stake: 60, Coefficient: 1.8}, ErrStakeLimitExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/bets", bytes.NewReader(body)))
+
+			var response struct {
+				Code string `json:"code"`
+			}
+			if err = json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("invalid response %q: %v", recorder.Body.String(), err)
+			}
+			if recorder.Code != tt.want.Status || response.Code != tt.want.Code {
+				t.Fatalf("got %d %s, want %d %s", recorder.Code, response.Code, tt.want.Status, tt.want.Code)
+			}
+		})
+	}
+}
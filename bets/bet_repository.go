@@ -0,0 +1,18 @@
+package bets
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"gorm.io/gorm"
+)
+
+type BetRepository struct {
+	db *gorm.DB
+}
+
+func NewBetRepository(db *gorm.DB) *BetRepository {
+	return &BetRepository{db: db}
+}
+
+func (r *BetRepository) CreateBet(bet *models.Bet) error {
+	return r.db.Create(bet).Error
+}
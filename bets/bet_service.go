@@ -0,0 +1,106 @@
+package bets
+
+import (
+	"errors"
+	"github.com/VaheMuradyan/Live2/cache"
+	"github.com/VaheMuradyan/Live2/db/models"
+	"github.com/VaheMuradyan/Live2/liability"
+	"log"
+	"math"
+	"os"
+	"strconv"
+)
+
+const (
+	defaultOddsTolerance = 0.02
+	defaultMaxStake      = 1000.0
+)
+
+type BetService struct {
+	repo          *BetRepository
+	cache         *cache.Cache
+	liability     *liability.Tracker
+	oddsTolerance float64
+	maxStake      float64
+}
+
+func NewBetService(repo *BetRepository, priceCache *cache.Cache, tracker *liability.Tracker) *BetService {
+	return &BetService{
+		repo:          repo,
+		cache:         priceCache,
+		liability:     tracker,
+		oddsTolerance: envFloat("BET_ODDS_TOLERANCE", defaultOddsTolerance),
+		maxStake:      envFloat("BET_MAX_STAKE", defaultMaxStake),
+	}
+}
+
+func (s *BetService) PlaceBet(req models.PlaceBetRequest) (models.Bet, error) {
+	if req.EventCode == "" || req.PriceCode == "" || req.Coefficient <= 0 {
+		return models.Bet{}, ErrInvalidRequest
+	}
+	if req.Stake <= 0 {
+		return models.Bet{}, ErrInvalidStake
+	}
+
+	eventPrice, err := s.cache.GetEventPriceByCode(req.EventCode, req.PriceCode)
+	if errors.Is(err, cache.ErrEventNotFound) {
+		return models.Bet{}, ErrEventNotFound
+	}
+	if err != nil {
+		return models.Bet{}, ErrPriceNotFound
+	}
+
+	switch s.cache.PriceStatus(eventPrice) {
+	case models.PriceStatusClosed:
+		return models.Bet{}, ErrPriceClosed
+	case models.PriceStatusSuspended:
+		return models.Bet{}, ErrPriceSuspended
+	}
+
+	if math.Abs(eventPrice.Coefficient-req.Coefficient)/req.Coefficient > s.oddsTolerance {
+		return models.Bet{}, ErrOddsChanged
+	}
+
+	if req.Stake > s.stakeLimit(eventPrice.Price.Market) {
+		return models.Bet{}, ErrStakeLimitExceeded
+	}
+
+	bet := models.Bet{
+		EventID:              eventPrice.EventID,
+		EventPriceID:         eventPrice.ID,
+		PriceID:              eventPrice.PriceID,
+		Stake:                req.Stake,
+		Coefficient:          eventPrice.Coefficient,
+		RequestedCoefficient: req.Coefficient,
+	}
+
+	if err = s.repo.CreateBet(&bet); err != nil {
+		log.Printf("Error storing bet on event price %d: %v", eventPrice.ID, err)
+		return models.Bet{}, ErrBetNotStored
+	}
+
+	s.liability.Record(bet)
+
+	return bet, nil
+}
+
+func (s *BetService) stakeLimit(market models.Market) float64 {
+	if market.MaxStake > 0 {
+		return market.MaxStake
+	}
+	return s.maxStake
+}
+
+func envFloat(key string, fallback float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Invalid %s value %q, using %v", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
@@ -1,18 +1,47 @@
 package cache
 
 import (
+	"errors"
+	"fmt"
 	"github.com/VaheMuradyan/Live2/db/models"
+	"github.com/VaheMuradyan/Live2/generator/markets"
 	"gorm.io/gorm"
 	"log"
 	"sync"
+	"sync/atomic"
+)
+
+var (
+	ErrEventNotFound = errors.New("event not found in cache")
+	ErrPriceNotFound = errors.New("price not found in cache")
+
+	ErrPricesNotFound = errors.New("event prices not found in store")
 )
 
 type Cache struct {
 	db           *gorm.DB
-	redis        *RedisCache
+	store        PriceStore
 	mu           sync.RWMutex
+	reloadMu     sync.Mutex
 	eventsMap    map[uint]models.Event
 	staticLookup map[uint]StaticEventData
+	suspensions  map[uint]Suspension
+	scores       map[uint]models.ScoreSnapshot
+	unpriced     map[uint]bool
+	removed      map[uint]bool
+	priceMisses  atomic.Int64
+}
+
+type PriceUpdate struct {
+	PriceID         uint
+	Coefficient     float64
+	FairCoefficient float64
+}
+
+type PriceChange struct {
+	PriceID        uint
+	OldCoefficient float64
+	NewCoefficient float64
 }
 
 type StaticEventData struct {
@@ -26,49 +55,115 @@ type StaticEventData struct {
 }
 
 type PriceRelation struct {
-	PriceID              uint
-	PriceName            string
-	PriceCode            string
-	MarketCode           string
-	MarketName           string
-	MarketCollectionCode string
-	MarketCollectionName string
+	PriceID                uint
+	PriceName              string
+	PriceCode              string
+	MarketCode             string
+	MarketName             string
+	MarketCollectionCode   string
+	MarketCollectionName   string
+	MarketMargin           float64
+	MarketCollectionMargin float64
+	MarketMaxStake         float64
 }
 
 func NewCache(db *gorm.DB) *Cache {
+	return NewCacheWithStore(db, NewPriceStore())
+}
+
+func NewCacheWithStore(db *gorm.DB, store PriceStore) *Cache {
 	cache := &Cache{
 		db:           db,
-		redis:        NewRedisCache(),
+		store:        store,
 		eventsMap:    make(map[uint]models.Event),
 		staticLookup: make(map[uint]StaticEventData),
+		suspensions:  make(map[uint]Suspension),
+		scores:       make(map[uint]models.ScoreSnapshot),
+		unpriced:     make(map[uint]bool),
+		removed:      make(map[uint]bool),
 	}
 	return cache
 }
 
 func (c *Cache) LoadStaticEventData() {
+	snapshot, err := c.loadStaticSnapshot()
+	if err != nil {
+		log.Printf("Error loading static event data: %v", err)
+		return
+	}
+
+	c.loadSnapshot(snapshot)
+}
+
+// LoadEvents replaces the cached events and their prices with the given
+// ones, as LoadStaticEventData does with those it reads from MySQL.
+func (c *Cache) LoadEvents(events []models.Event, eventPrices []models.EventPrice) {
+	c.loadSnapshot(newStaticSnapshot(events, eventPrices))
+}
+
+func (c *Cache) loadSnapshot(snapshot staticSnapshot) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.eventsMap = snapshot.events
+	c.staticLookup = snapshot.staticLookup
+	c.suspensions = make(map[uint]Suspension)
+	c.scores = make(map[uint]models.ScoreSnapshot)
+	c.unpriced = make(map[uint]bool)
+	c.removed = make(map[uint]bool)
+	c.mu.Unlock()
+
+	for eventID, prices := range snapshot.prices {
+		if err := c.store.SetEventPrices(eventID, prices); err != nil {
+			log.Printf("Error storing event prices for event %d: %v", eventID, err)
+		}
+	}
+}
 
+// staticSnapshot is the static data of all active events as read from
+// MySQL, with their prices grouped by event.
+type staticSnapshot struct {
+	events       map[uint]models.Event
+	staticLookup map[uint]StaticEventData
+	prices       map[uint][]models.EventPrice
+}
+
+func (c *Cache) loadStaticSnapshot() (staticSnapshot, error) {
 	var events []models.Event
 	err := c.db.Where("events.active = ?", true).
 		Preload("Competition").
 		Preload("Competition.Country").
 		Preload("Competition.Country.Sport").
-		Preload("Teams").
+		Preload("Teams", func(db *gorm.DB) *gorm.DB {
+			return db.Order("teams.id")
+		}).
 		Find(&events).Error
 
 	if err != nil {
-		log.Printf("Error loading events: %v", err)
-		return
+		return staticSnapshot{}, fmt.Errorf("failed to load events: %w", err)
 	}
 
-	c.eventsMap = make(map[uint]models.Event)
-	c.staticLookup = make(map[uint]StaticEventData)
+	eventPrices, err := c.queryEventPrices()
+	if err != nil {
+		return staticSnapshot{}, fmt.Errorf("failed to load price relations: %w", err)
+	}
+
+	return newStaticSnapshot(events, eventPrices), nil
+}
+
+func newStaticSnapshot(events []models.Event, eventPrices []models.EventPrice) staticSnapshot {
+	snapshot := staticSnapshot{
+		events:       make(map[uint]models.Event),
+		staticLookup: make(map[uint]StaticEventData),
+		prices:       make(map[uint][]models.EventPrice),
+	}
 
 	for _, event := range events {
-		c.eventsMap[event.ID] = event
+		snapshot.events[event.ID] = event
 
-		staticData := StaticEventData{
+		if _, _, ok := eventTeams(event); !ok {
+			log.Printf("Event %s has no distinct home and away team, pricing it as evenly matched", event.Code)
+		}
+
+		snapshot.staticLookup[event.ID] = StaticEventData{
 			EventID:         event.ID,
 			EventName:       event.Name,
 			EventCode:       event.Code,
@@ -77,54 +172,179 @@ func (c *Cache) LoadStaticEventData() {
 			SportName:       event.Competition.Country.Sport.Name,
 			PriceRelations:  make(map[uint]PriceRelation),
 		}
+	}
 
-		c.staticLookup[event.ID] = staticData
+	for _, ep := range eventPrices {
+		if staticData, exists := snapshot.staticLookup[ep.EventID]; exists {
+			staticData.PriceRelations[ep.PriceID] = newPriceRelation(ep)
+		}
+		snapshot.prices[ep.EventID] = append(snapshot.prices[ep.EventID], ep)
 	}
 
-	c.loadPriceRelations()
+	return snapshot
 }
 
-func (c *Cache) loadPriceRelations() {
-	var eventPrices []models.EventPrice
-	err := c.db.Preload("Price").
+// queryEventPrices loads the prices of active markets of the given active
+// events from MySQL, or of all active events when none are given.
+func (c *Cache) queryEventPrices(eventIDs ...uint) ([]models.EventPrice, error) {
+	query := c.db.Preload("Price").
 		Preload("Price.Market").
 		Preload("Price.Market.MarketCollection").
 		Joins("JOIN events ON event_prices.event_id = events.id").
 		Joins("JOIN prices ON event_prices.price_id = prices.id").
 		Joins("JOIN markets ON prices.market_id = markets.id").
 		Where("events.active = ? AND markets.active = ?",
-			true, true).
-		Find(&eventPrices).Error
+			true, true)
+
+	if len(eventIDs) > 0 {
+		query = query.Where("event_prices.event_id IN ?", eventIDs)
+	}
+
+	var eventPrices []models.EventPrice
+	err := query.Find(&eventPrices).Error
+	return eventPrices, err
+}
+
+// rebuildEventPrices writes the event's prices back to the store from MySQL
+// after they went missing. MySQL holds them as they were loaded, so prices
+// the market rules closed since are closed again at the event's last score.
+// An event MySQL has no prices for is remembered so it is not queried again.
+func (c *Cache) rebuildEventPrices(eventID uint) error {
+	misses := c.priceMisses.Add(1)
+	log.Printf("Prices of event %d missing from the store, rebuilding from MySQL (%d misses so far)", eventID, misses)
 
+	eventPrices, err := c.queryEventPrices(eventID)
 	if err != nil {
-		log.Printf("Error loading price relations: %v", err)
-		return
+		return err
 	}
 
-	eventPricesMap := make(map[uint][]models.EventPrice)
+	c.mu.Lock()
+	event := c.eventsMap[eventID]
+	score, scored := c.scores[eventID]
+	if len(eventPrices) == 0 {
+		c.unpriced[eventID] = true
+	}
+	c.mu.Unlock()
 
-	for _, ep := range eventPrices {
-		if staticData, exists := c.staticLookup[ep.EventID]; exists {
-			staticData.PriceRelations[ep.PriceID] = PriceRelation{
-				PriceID:              ep.PriceID,
-				PriceName:            ep.Price.Name,
-				PriceCode:            ep.Price.Code,
-				MarketCode:           ep.Price.Market.Code,
-				MarketName:           ep.Price.Market.Name,
-				MarketCollectionCode: ep.Price.Market.MarketCollection.Code,
-				MarketCollectionName: ep.Price.Market.MarketCollection.Name,
+	if scored {
+		closeSettledPrices(markets.NormalizeSport(event.Competition.Country.Sport.Code), score, eventPrices)
+	}
+	return c.store.SetEventPrices(eventID, eventPrices)
+}
+
+// closeSettledPrices marks the prices the market rules close at the score
+// inactive, as the generator does on every score change.
+func closeSettledPrices(sport string, score models.ScoreSnapshot, eventPrices []models.EventPrice) {
+	closed := make(map[string]map[string]bool)
+
+	for i, eventPrice := range eventPrices {
+		marketCode := eventPrice.Price.Market.Code
+
+		calculator, exists := markets.Lookup(marketCode)
+		if !exists || markets.MarketSport(marketCode) != sport {
+			continue
+		}
+
+		if _, done := closed[marketCode]; !done {
+			closed[marketCode] = make(map[string]bool)
+			for _, priceCode := range calculator.ClosedPrices(marketCode, score) {
+				closed[marketCode][priceCode] = true
 			}
-			c.staticLookup[ep.EventID] = staticData
 		}
-		eventPricesMap[ep.EventID] = append(eventPricesMap[ep.EventID], ep)
+		if closed[marketCode][eventPrice.Price.Code] {
+			eventPrices[i].Active = false
+		}
 	}
+}
 
-	for eventID, prices := range eventPricesMap {
-		err = c.redis.SetEventPrices(eventID, prices)
-		if err != nil {
-			log.Printf("Error setting event prices in Redis for event %d: %v", eventID, err)
+// SetEventScore records the event's latest score, which rebuilt prices are
+// closed against.
+func (c *Cache) SetEventScore(score models.ScoreSnapshot) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.scores[score.EventID] = score
+}
+
+// GetEventScore returns the event's latest recorded score.
+func (c *Cache) GetEventScore(eventID uint) (models.ScoreSnapshot, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	score, exists := c.scores[eventID]
+	return score, exists
+}
+
+// withEventPrices runs fn against the event's stored prices and, when they
+// are missing while the event is still active and has prices in MySQL,
+// rebuilds them and runs it again.
+func (c *Cache) withEventPrices(eventID uint, fn func() error) error {
+	err := fn()
+	if !errors.Is(err, ErrPricesNotFound) {
+		return err
+	}
+
+	c.mu.RLock()
+	_, active := c.eventsMap[eventID]
+	unpriced := c.unpriced[eventID]
+	c.mu.RUnlock()
+	if !active || unpriced {
+		return err
+	}
+
+	if err = c.rebuildEventPrices(eventID); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// PriceMisses counts how often an active event's prices had to be rebuilt
+// because they were missing from the store.
+func (c *Cache) PriceMisses() int64 {
+	return c.priceMisses.Load()
+}
+
+// EndEvent removes a finished event from the cache and deletes its stored
+// prices. The prices should be saved to MySQL first.
+func (c *Cache) EndEvent(eventID uint) {
+	c.mu.Lock()
+	delete(c.eventsMap, eventID)
+	delete(c.staticLookup, eventID)
+	delete(c.suspensions, eventID)
+	delete(c.scores, eventID)
+	delete(c.unpriced, eventID)
+	delete(c.removed, eventID)
+	c.mu.Unlock()
+
+	if err := c.store.DeleteEventPrices(eventID); err != nil {
+		log.Printf("Error deleting prices of event %d: %v", eventID, err)
+	}
+}
+
+// RetireEvent saves the live prices of an event that was deactivated while
+// running and then ends it.
+func (c *Cache) RetireEvent(eventID uint) {
+	if eventPrices := c.GetEventPrices(eventID, false); len(eventPrices) > 0 {
+		if err := c.db.Save(eventPrices).Error; err != nil {
+			log.Printf("Error saving prices of event %d: %v", eventID, err)
 		}
 	}
+	c.EndEvent(eventID)
+}
+
+func newPriceRelation(ep models.EventPrice) PriceRelation {
+	return PriceRelation{
+		PriceID:                ep.PriceID,
+		PriceName:              ep.Price.Name,
+		PriceCode:              ep.Price.Code,
+		MarketCode:             ep.Price.Market.Code,
+		MarketName:             ep.Price.Market.Name,
+		MarketCollectionCode:   ep.Price.Market.MarketCollection.Code,
+		MarketCollectionName:   ep.Price.Market.MarketCollection.Name,
+		MarketMargin:           ep.Price.Market.Margin,
+		MarketCollectionMargin: ep.Price.Market.MarketCollection.Margin,
+		MarketMaxStake:         ep.Price.Market.MaxStake,
+	}
 }
 
 func (c *Cache) GetActiveEvents() []models.Event {
@@ -138,8 +358,54 @@ func (c *Cache) GetActiveEvents() []models.Event {
 	return events
 }
 
+func (c *Cache) GetEvent(eventID uint) (models.Event, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	event, exists := c.eventsMap[eventID]
+	return event, exists
+}
+
+// GetEventTeams returns the event's home and away teams, or false when the
+// event does not name two distinct teams among its own.
+func (c *Cache) GetEventTeams(eventID uint) (models.Team, models.Team, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return eventTeams(c.eventsMap[eventID])
+}
+
+func eventTeams(event models.Event) (models.Team, models.Team, bool) {
+	var home, away models.Team
+	for _, team := range event.Teams {
+		switch team.ID {
+		case event.HomeTeamID:
+			home = team
+		case event.AwayTeamID:
+			away = team
+		}
+	}
+	return home, away, home.ID != 0 && away.ID != 0 && home.ID != away.ID
+}
+
+func (c *Cache) GetEventByCode(eventCode string) (models.Event, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, event := range c.eventsMap {
+		if event.Code == eventCode {
+			return event, true
+		}
+	}
+	return models.Event{}, false
+}
+
 func (c *Cache) GetEventPrices(eventID uint, forCentrifugo bool) []models.EventPrice {
-	eventPrices, err := c.redis.GetEventPrices(eventID)
+	var eventPrices []models.EventPrice
+	err := c.withEventPrices(eventID, func() (err error) {
+		eventPrices, err = c.store.GetEventPrices(eventID)
+		return err
+	})
 	if err != nil {
 		return []models.EventPrice{}
 	}
@@ -150,6 +416,40 @@ func (c *Cache) GetEventPrices(eventID uint, forCentrifugo bool) []models.EventP
 	return eventPrices
 }
 
+func (c *Cache) GetEventPriceByCode(eventCode, priceCode string) (models.EventPrice, error) {
+	eventID, priceID, err := c.lookupCodes(eventCode, priceCode)
+	if err != nil {
+		return models.EventPrice{}, err
+	}
+
+	for _, eventPrice := range c.GetEventPrices(eventID, true) {
+		if eventPrice.PriceID == priceID {
+			return eventPrice, nil
+		}
+	}
+
+	return models.EventPrice{}, ErrPriceNotFound
+}
+
+func (c *Cache) lookupCodes(eventCode, priceCode string) (uint, uint, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for eventID, staticData := range c.staticLookup {
+		if staticData.EventCode != eventCode {
+			continue
+		}
+		for priceID, priceRel := range staticData.PriceRelations {
+			if priceRel.PriceCode == priceCode {
+				return eventID, priceID, nil
+			}
+		}
+		return 0, 0, ErrPriceNotFound
+	}
+
+	return 0, 0, ErrEventNotFound
+}
+
 func (c *Cache) enrichEventPricesForCentrifugo(eventPrices []models.EventPrice, eventID uint) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -173,11 +473,14 @@ func (c *Cache) enrichEventPricesForCentrifugo(eventPrices []models.EventPrice,
 				Name:  priceRel.PriceName,
 				Code:  priceRel.PriceCode,
 				Market: models.Market{
-					Name: priceRel.MarketName,
-					Code: priceRel.MarketCode,
+					Name:     priceRel.MarketName,
+					Code:     priceRel.MarketCode,
+					Margin:   priceRel.MarketMargin,
+					MaxStake: priceRel.MarketMaxStake,
 					MarketCollection: models.MarketCollection{
-						Name: priceRel.MarketCollectionName,
-						Code: priceRel.MarketCollectionCode,
+						Name:   priceRel.MarketCollectionName,
+						Code:   priceRel.MarketCollectionCode,
+						Margin: priceRel.MarketCollectionMargin,
 					},
 				},
 			}
@@ -185,64 +488,57 @@ func (c *Cache) enrichEventPricesForCentrifugo(eventPrices []models.EventPrice,
 	}
 }
 
-func (c *Cache) UpdateEventPriceCoefficient(eventID, priceID uint, newCoefficient float64) error {
-	eventPrices, err := c.redis.GetEventPrices(eventID)
-	if err != nil {
+// UpdateEventPrices applies all coefficient updates of one score change to
+// the event in a single round trip and returns what each price changed
+// from and to, keyed by PriceID.
+func (c *Cache) UpdateEventPrices(eventID uint, updates []PriceUpdate) (map[uint]PriceChange, error) {
+	var previous map[uint]float64
+	err := c.withEventPrices(eventID, func() (err error) {
+		previous, err = c.store.UpdateEventPrices(eventID, updates)
 		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	for i := range eventPrices {
-		if eventPrices[i].PriceID == priceID {
-			eventPrices[i].Coefficient = newCoefficient
-			break
+	changes := make(map[uint]PriceChange, len(previous))
+	for _, update := range updates {
+		if old, ok := previous[update.PriceID]; ok {
+			changes[update.PriceID] = PriceChange{
+				PriceID:        update.PriceID,
+				OldCoefficient: old,
+				NewCoefficient: update.Coefficient,
+			}
 		}
 	}
-
-	return c.redis.SetEventPrices(eventID, eventPrices)
+	return changes, nil
 }
 
 func (c *Cache) DeactivateEventPrices(eventID uint, priceIDs []uint) error {
-	eventPrices, err := c.redis.GetEventPrices(eventID)
-	if err != nil {
-		return err
-	}
-
-	priceIDSet := make(map[uint]bool)
-	for _, id := range priceIDs {
-		priceIDSet[id] = true
-	}
-
-	for i := range eventPrices {
-		if priceIDSet[eventPrices[i].PriceID] {
-			eventPrices[i].Active = false
-		}
-	}
-
-	return c.redis.SetEventPrices(eventID, eventPrices)
+	return c.withEventPrices(eventID, func() error {
+		return c.store.DeactivateEventPrices(eventID, priceIDs)
+	})
 }
 
-func (c *Cache) GetPriceIDsByCodes(codes []string) []uint {
+func (c *Cache) GetEventMarketPrices(eventID uint) map[string]map[string]uint {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	codeSet := make(map[string]bool)
-	for _, code := range codes {
-		codeSet[code] = true
-	}
+	marketPrices := make(map[string]map[string]uint)
 
-	var priceIDs []uint
-	priceIDSet := make(map[uint]bool)
+	staticData, exists := c.staticLookup[eventID]
+	if !exists {
+		return marketPrices
+	}
 
-	for _, staticData := range c.staticLookup {
-		for priceID, priceRel := range staticData.PriceRelations {
-			if codeSet[priceRel.PriceCode] && !priceIDSet[priceID] {
-				priceIDs = append(priceIDs, priceID)
-				priceIDSet[priceID] = true
-			}
+	for priceID, priceRel := range staticData.PriceRelations {
+		if _, ok := marketPrices[priceRel.MarketCode]; !ok {
+			marketPrices[priceRel.MarketCode] = make(map[string]uint)
 		}
+		marketPrices[priceRel.MarketCode][priceRel.PriceCode] = priceID
 	}
 
-	return priceIDs
+	return marketPrices
 }
 
 func (c *Cache) GetAllScoreSnapshotsForSimulation() []models.ScoreSnapshot {
@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"github.com/VaheMuradyan/Live2/generator/markets"
+	"testing"
+)
+
+func TestUnpricedEventIsNotRebuiltOnEveryRead(t *testing.T) {
+	c := NewCacheWithStore(nil, NewMemoryPriceStore())
+	c.eventsMap[1] = models.Event{Code: "MA"}
+	c.unpriced[1] = true
+
+	for range 3 {
+		if eventPrices := c.GetEventPrices(1, false); len(eventPrices) != 0 {
+			t.Fatalf("got prices %+v for an event without any", eventPrices)
+		}
+	}
+	if misses := c.PriceMisses(); misses != 0 {
+		t.Fatalf("rebuilt %d times, want 0", misses)
+	}
+}
+
+func TestCloseSettledPricesAtScore(t *testing.T) {
+	eventPrice := func(marketCode, priceCode string) models.EventPrice {
+		return models.EventPrice{
+			Price:  models.Price{Code: priceCode, Market: models.Market{Code: marketCode}},
+			Active: true,
+		}
+	}
+	eventPrices := []models.EventPrice{
+		eventPrice("1X2", "1"),
+		eventPrice("OU25", "O25"),
+		eventPrice("OU25", "U25"),
+		eventPrice("OU45", "O45"),
+		eventPrice("BTTS", "BTTS_Y"),
+	}
+
+	closeSettledPrices(markets.SportFootball, models.ScoreSnapshot{Team1Score: 3, Total: 3, Minute: 60}, eventPrices)
+
+	want := []bool{true, false, false, true, true}
+	for i, eventPrice := range eventPrices {
+		if eventPrice.Active != want[i] {
+			t.Errorf("%s %s active = %v, want %v", eventPrice.Price.Market.Code, eventPrice.Price.Code, eventPrice.Active, want[i])
+		}
+	}
+}
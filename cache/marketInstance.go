@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"github.com/VaheMuradyan/Live2/generator/markets"
+	"gorm.io/gorm"
+)
+
+// AddMarketInstance creates a market that is opened while the event is
+// running, offers its prices on the event and adds them to the event's
+// stored prices.
+func (c *Cache) AddMarketInstance(eventID uint, definition markets.MarketDefinition) error {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	if _, exists := c.GetEvent(eventID); !exists {
+		return ErrEventNotFound
+	}
+
+	var created []models.EventPrice
+
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		var collection models.MarketCollection
+		err := tx.Where(models.MarketCollection{Code: definition.CollectionCode}).
+			Attrs(models.MarketCollection{Name: definition.CollectionName}).
+			FirstOrCreate(&collection).Error
+		if err != nil {
+			return err
+		}
+
+		var market models.Market
+		err = tx.Where(models.Market{Code: definition.Code}).
+			Attrs(models.Market{Name: definition.Name, MarketCollectionID: collection.ID}).
+			Assign(models.Market{Active: true}).
+			FirstOrCreate(&market).Error
+		if err != nil {
+			return err
+		}
+		market.MarketCollection = collection
+
+		for _, priceDefinition := range definition.Prices {
+			var price models.Price
+			err = tx.Where(models.Price{Code: priceDefinition.Code}).
+				Attrs(models.Price{Name: priceDefinition.Name, MarketID: market.ID}).
+				FirstOrCreate(&price).Error
+			if err != nil {
+				return err
+			}
+
+			var eventPrice models.EventPrice
+			err = tx.Where(models.EventPrice{EventID: eventID, PriceID: price.ID}).
+				Assign(models.EventPrice{Active: true}).
+				FirstOrCreate(&eventPrice).Error
+			if err != nil {
+				return err
+			}
+
+			price.Market = market
+			eventPrice.Price = price
+			created = append(created, eventPrice)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	c.addPriceRelations(eventID, created)
+
+	return c.store.AddEventPrices(eventID, created)
+}
+
+func (c *Cache) addPriceRelations(eventID uint, eventPrices []models.EventPrice) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.unpriced, eventID)
+
+	staticData, exists := c.staticLookup[eventID]
+	if !exists {
+		return
+	}
+
+	for _, ep := range eventPrices {
+		staticData.PriceRelations[ep.PriceID] = newPriceRelation(ep)
+	}
+}
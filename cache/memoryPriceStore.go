@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"sort"
+	"sync"
+)
+
+// MemoryPriceStore keeps event prices in process memory for tests and
+// single-node runs without Redis.
+type MemoryPriceStore struct {
+	mu     sync.Mutex
+	events map[uint]map[uint]models.EventPrice
+}
+
+func NewMemoryPriceStore() *MemoryPriceStore {
+	return &MemoryPriceStore{
+		events: make(map[uint]map[uint]models.EventPrice),
+	}
+}
+
+func (m *MemoryPriceStore) SetEventPrices(eventID uint, eventPrices []models.EventPrice) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.events, eventID)
+	if len(eventPrices) == 0 {
+		return nil
+	}
+
+	prices := make(map[uint]models.EventPrice, len(eventPrices))
+	for _, ep := range eventPrices {
+		prices[ep.PriceID] = stripRelations(ep)
+	}
+	m.events[eventID] = prices
+
+	return nil
+}
+
+func (m *MemoryPriceStore) AddEventPrices(eventID uint, eventPrices []models.EventPrice) error {
+	if len(eventPrices) == 0 {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prices, exists := m.events[eventID]
+	if !exists {
+		prices = make(map[uint]models.EventPrice, len(eventPrices))
+		m.events[eventID] = prices
+	}
+
+	for _, ep := range eventPrices {
+		if _, known := prices[ep.PriceID]; !known {
+			prices[ep.PriceID] = stripRelations(ep)
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryPriceStore) GetEventPrices(eventID uint) ([]models.EventPrice, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prices, exists := m.events[eventID]
+	if !exists {
+		return nil, ErrPricesNotFound
+	}
+
+	eventPrices := make([]models.EventPrice, 0, len(prices))
+	for _, ep := range prices {
+		eventPrices = append(eventPrices, ep)
+	}
+
+	sort.Slice(eventPrices, func(i, j int) bool {
+		return eventPrices[i].ID < eventPrices[j].ID
+	})
+
+	return eventPrices, nil
+}
+
+func (m *MemoryPriceStore) UpdateEventPrices(eventID uint, updates []PriceUpdate) (map[uint]float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prices, exists := m.events[eventID]
+	if !exists {
+		return nil, ErrPricesNotFound
+	}
+
+	previous := make(map[uint]float64, len(updates))
+	for _, update := range updates {
+		ep, known := prices[update.PriceID]
+		if !known {
+			continue
+		}
+
+		previous[update.PriceID] = ep.Coefficient
+		ep.Coefficient = update.Coefficient
+		ep.FairCoefficient = update.FairCoefficient
+		prices[update.PriceID] = ep
+	}
+
+	return previous, nil
+}
+
+func (m *MemoryPriceStore) DeactivateEventPrices(eventID uint, priceIDs []uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prices, exists := m.events[eventID]
+	if !exists {
+		return ErrPricesNotFound
+	}
+
+	for _, priceID := range priceIDs {
+		if ep, known := prices[priceID]; known {
+			ep.Active = false
+			prices[priceID] = ep
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryPriceStore) RemoveEventPrices(eventID uint, priceIDs []uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prices, exists := m.events[eventID]
+	if !exists {
+		return nil
+	}
+
+	for _, priceID := range priceIDs {
+		delete(prices, priceID)
+	}
+	if len(prices) == 0 {
+		delete(m.events, eventID)
+	}
+
+	return nil
+}
+
+func (m *MemoryPriceStore) DeleteEventPrices(eventID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.events, eventID)
+	return nil
+}
+
+// stripRelations keeps the fields the Redis store keeps, so both stores
+// return the same prices.
+func stripRelations(ep models.EventPrice) models.EventPrice {
+	return fromRedisPrice(toRedisPrice(ep))
+}
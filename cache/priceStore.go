@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"log"
+	"os"
+)
+
+const (
+	PriceStoreRedis  = "redis"
+	PriceStoreMemory = "memory"
+)
+
+// PriceStore keeps the live prices of running events. Reads and updates of
+// an event with no stored prices return ErrPricesNotFound.
+type PriceStore interface {
+	SetEventPrices(eventID uint, eventPrices []models.EventPrice) error
+	AddEventPrices(eventID uint, eventPrices []models.EventPrice) error
+	GetEventPrices(eventID uint) ([]models.EventPrice, error)
+	UpdateEventPrices(eventID uint, updates []PriceUpdate) (map[uint]float64, error)
+	DeactivateEventPrices(eventID uint, priceIDs []uint) error
+	RemoveEventPrices(eventID uint, priceIDs []uint) error
+	DeleteEventPrices(eventID uint) error
+}
+
+// NewPriceStore returns the store named by PRICE_STORE, Redis by default.
+func NewPriceStore() PriceStore {
+	switch store := os.Getenv("PRICE_STORE"); store {
+	case PriceStoreMemory:
+		return NewMemoryPriceStore()
+	case "", PriceStoreRedis:
+		return NewRedisCache()
+	default:
+		log.Printf("Unknown PRICE_STORE %q, using %s", store, PriceStoreRedis)
+		return NewRedisCache()
+	}
+}
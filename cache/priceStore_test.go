@@ -0,0 +1,220 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/VaheMuradyan/Live2/db/models"
+	"gorm.io/gorm"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// priceStores returns every store the contract runs against: always the
+// in-memory one and Redis when REDIS_HOST points at a server.
+func priceStores(t *testing.T) map[string]PriceStore {
+	t.Helper()
+
+	stores := map[string]PriceStore{PriceStoreMemory: NewMemoryPriceStore()}
+	if os.Getenv("REDIS_HOST") != "" {
+		stores[PriceStoreRedis] = NewRedisCache()
+	}
+	return stores
+}
+
+func testPrice(id, priceID uint, coefficient float64) models.EventPrice {
+	return models.EventPrice{
+		Model:           gorm.Model{ID: id},
+		EventID:         testEventID,
+		PriceID:         priceID,
+		Coefficient:     coefficient,
+		FairCoefficient: coefficient,
+		Active:          true,
+	}
+}
+
+const testEventID = 900001
+
+func TestPriceStoreContract(t *testing.T) {
+	for name, store := range priceStores(t) {
+		t.Run(name, func(t *testing.T) {
+			_ = store.DeleteEventPrices(testEventID)
+			t.Cleanup(func() { _ = store.DeleteEventPrices(testEventID) })
+
+			expectMissing := func(step string) {
+				t.Helper()
+				if _, err := store.GetEventPrices(testEventID); !errors.Is(err, ErrPricesNotFound) {
+					t.Fatalf("%s: got %v, want ErrPricesNotFound", step, err)
+				}
+			}
+			get := func() []models.EventPrice {
+				t.Helper()
+				eventPrices, err := store.GetEventPrices(testEventID)
+				if err != nil {
+					t.Fatalf("get: %v", err)
+				}
+				return eventPrices
+			}
+
+			expectMissing("unknown event")
+			if _, err := store.UpdateEventPrices(testEventID, []PriceUpdate{{PriceID: 1, Coefficient: 2}}); !errors.Is(err, ErrPricesNotFound) {
+				t.Fatalf("update unknown event: got %v, want ErrPricesNotFound", err)
+			}
+			if err := store.DeactivateEventPrices(testEventID, []uint{1}); !errors.Is(err, ErrPricesNotFound) {
+				t.Fatalf("deactivate unknown event: got %v, want ErrPricesNotFound", err)
+			}
+
+			if err := store.AddEventPrices(testEventID, nil); err != nil {
+				t.Fatalf("add nothing: %v", err)
+			}
+			expectMissing("add nothing")
+
+			if err := store.SetEventPrices(testEventID, []models.EventPrice{testPrice(12, 2, 3.5), testPrice(11, 1, 1.8)}); err != nil {
+				t.Fatalf("set: %v", err)
+			}
+			want := []models.EventPrice{testPrice(11, 1, 1.8), testPrice(12, 2, 3.5)}
+			if got := get(); !reflect.DeepEqual(got, want) {
+				t.Fatalf("after set got %+v, want %+v", got, want)
+			}
+
+			if err := store.AddEventPrices(testEventID, []models.EventPrice{testPrice(11, 1, 9.9), testPrice(13, 3, 4.2)}); err != nil {
+				t.Fatalf("add: %v", err)
+			}
+			want = append(want, testPrice(13, 3, 4.2))
+			if got := get(); !reflect.DeepEqual(got, want) {
+				t.Fatalf("add overwrote a known price or missed a new one: got %+v, want %+v", got, want)
+			}
+
+			previous, err := store.UpdateEventPrices(testEventID, []PriceUpdate{
+				{PriceID: 1, Coefficient: 2.1, FairCoefficient: 2},
+				{PriceID: 99, Coefficient: 5, FairCoefficient: 5},
+			})
+			if err != nil {
+				t.Fatalf("update: %v", err)
+			}
+			if want := map[uint]float64{1: 1.8}; !reflect.DeepEqual(previous, want) {
+				t.Fatalf("update returned %v, want %v", previous, want)
+			}
+			if got := get()[0]; got.Coefficient != 2.1 || got.FairCoefficient != 2 {
+				t.Fatalf("update stored %v/%v, want 2.1/2", got.Coefficient, got.FairCoefficient)
+			}
+
+			if err = store.DeactivateEventPrices(testEventID, []uint{2, 99}); err != nil {
+				t.Fatalf("deactivate: %v", err)
+			}
+			if got := get(); got[0].Active != true || got[1].Active != false || got[2].Active != true {
+				t.Fatalf("deactivate changed the wrong prices: %+v", got)
+			}
+
+			if err = store.RemoveEventPrices(testEventID, []uint{1}); err != nil {
+				t.Fatalf("remove: %v", err)
+			}
+			if got := get(); len(got) != 2 || got[0].PriceID != 2 || got[1].PriceID != 3 {
+				t.Fatalf("remove left %+v", got)
+			}
+
+			if err = store.DeleteEventPrices(testEventID); err != nil {
+				t.Fatalf("delete: %v", err)
+			}
+			expectMissing("delete")
+
+			if err = store.SetEventPrices(testEventID, nil); err != nil {
+				t.Fatalf("set nothing: %v", err)
+			}
+			expectMissing("set nothing")
+		})
+	}
+}
+
+// TestRedisMigratesLegacyPrices stores prices in the old JSON list format and
+// checks that every way of reaching them moves them into the hash first.
+func TestRedisMigratesLegacyPrices(t *testing.T) {
+	if os.Getenv("REDIS_HOST") == "" {
+		t.Skip("REDIS_HOST is not set")
+	}
+	store := NewRedisCache()
+
+	legacy := []models.EventPrice{testPrice(11, 1, 1.8), testPrice(12, 2, 3.5)}
+
+	tests := []struct {
+		name  string
+		apply func() error
+		want  []models.EventPrice
+	}{
+		{
+			name: "get",
+			apply: func() error {
+				_, err := store.GetEventPrices(testEventID)
+				return err
+			},
+			want: legacy,
+		},
+		{
+			name: "update script",
+			apply: func() error {
+				previous, err := store.UpdateEventPrices(testEventID, []PriceUpdate{{PriceID: 2, Coefficient: 3, FairCoefficient: 2.9}})
+				if err == nil && !reflect.DeepEqual(previous, map[uint]float64{2: 3.5}) {
+					t.Errorf("update returned %v", previous)
+				}
+				return err
+			},
+			want: func() []models.EventPrice {
+				updated := testPrice(12, 2, 3)
+				updated.FairCoefficient = 2.9
+				return []models.EventPrice{testPrice(11, 1, 1.8), updated}
+			}(),
+		},
+		{
+			name: "deactivate script",
+			apply: func() error {
+				return store.DeactivateEventPrices(testEventID, []uint{1})
+			},
+			want: func() []models.EventPrice {
+				closed := testPrice(11, 1, 1.8)
+				closed.Active = false
+				return []models.EventPrice{closed, testPrice(12, 2, 3.5)}
+			}(),
+		},
+		{
+			name: "add",
+			apply: func() error {
+				return store.AddEventPrices(testEventID, []models.EventPrice{testPrice(11, 1, 9.9), testPrice(13, 3, 4.2)})
+			},
+			want: append(append([]models.EventPrice{}, legacy...), testPrice(13, 3, 4.2)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = store.DeleteEventPrices(testEventID)
+			t.Cleanup(func() { _ = store.DeleteEventPrices(testEventID) })
+
+			data, err := json.Marshal([]EventPriceRedis{toRedisPrice(legacy[0]), toRedisPrice(legacy[1])})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = store.client.Set(store.ctx, legacyEventPricesKey(testEventID), data, 0).Err(); err != nil {
+				t.Fatalf("store legacy list: %v", err)
+			}
+
+			if err = tt.apply(); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+
+			if exists, err := store.client.Exists(store.ctx, legacyEventPricesKey(testEventID)).Result(); err != nil || exists != 0 {
+				t.Fatalf("legacy list still there (%d, %v)", exists, err)
+			}
+			if fields, err := store.client.HLen(store.ctx, eventPricesKey(testEventID)).Result(); err != nil || int(fields) != len(tt.want) {
+				t.Fatalf("hash has %d fields (%v), want %d", fields, err, len(tt.want))
+			}
+
+			got, err := store.GetEventPrices(testEventID)
+			if err != nil {
+				t.Fatalf("get after migration: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,13 +3,15 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/VaheMuradyan/Live2/db/models"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 	"log"
 	"os"
-	"time"
+	"sort"
+	"strconv"
 )
 
 type RedisCache struct {
@@ -45,65 +47,290 @@ func NewRedisCache() *RedisCache {
 }
 
 type EventPriceRedis struct {
-	ID          uint    `json:"id"`
-	EventID     uint    `json:"event_id"`
-	PriceID     uint    `json:"price_id"`
-	Coefficient float64 `json:"coefficient"`
-	Active      bool    `json:"active"`
+	ID              uint    `json:"id"`
+	EventID         uint    `json:"event_id"`
+	PriceID         uint    `json:"price_id"`
+	Coefficient     float64 `json:"coefficient"`
+	FairCoefficient float64 `json:"fair_coefficient"`
+	Active          bool    `json:"active"`
 }
 
-func (r *RedisCache) SetEventPrices(eventID uint, eventPrices []models.EventPrice) error {
-	key := fmt.Sprintf("event_prices:%d", eventID)
-
-	simplifiedPrices := make([]EventPriceRedis, len(eventPrices))
-	for i, ep := range eventPrices {
-		simplifiedPrices[i] = EventPriceRedis{
-			ID:          ep.ID,
-			EventID:     ep.EventID,
-			PriceID:     ep.PriceID,
-			Coefficient: ep.Coefficient,
-			Active:      ep.Active,
+// updateCoefficientsScript sets the coefficients of the prices given as
+// (field, coefficient, fair coefficient) triples in ARGV and returns
+// each updated field followed by the coefficient it replaced. It returns -1
+// when the event has no hash.
+var updateCoefficientsScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return -1
+end
+local previous = {}
+for i = 1, #ARGV, 3 do
+	local value = redis.call('HGET', KEYS[1], ARGV[i])
+	if value then
+		local price = cjson.decode(value)
+		previous[#previous + 1] = ARGV[i]
+		previous[#previous + 1] = tostring(price.coefficient)
+		price.coefficient = tonumber(ARGV[i + 1])
+		price.fair_coefficient = tonumber(ARGV[i + 2])
+		redis.call('HSET', KEYS[1], ARGV[i], cjson.encode(price))
+	end
+end
+return previous
+`)
+
+// deactivateScript marks the prices in ARGV inactive. It returns -1
+// when the event has no hash.
+var deactivateScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return -1
+end
+for i = 1, #ARGV do
+	local value = redis.call('HGET', KEYS[1], ARGV[i])
+	if value then
+		local price = cjson.decode(value)
+		price.active = false
+		redis.call('HSET', KEYS[1], ARGV[i], cjson.encode(price))
+	end
+end
+return 1
+`)
+
+// eventPricesKey names the hash holding an event's prices, with one JSON
+// field per PriceID so a single price can be changed atomically without
+// rewriting the others. Earlier versions kept them as one JSON list under
+// legacyEventPricesKey, which is migrated to the hash the first time it is
+// read. The hash has no expiry; DeleteEventPrices removes it when the event
+// ends.
+func eventPricesKey(eventID uint) string {
+	return fmt.Sprintf("event_prices_hash:%d", eventID)
+}
+
+func legacyEventPricesKey(eventID uint) string {
+	return fmt.Sprintf("event_prices:%d", eventID)
+}
+
+func priceField(priceID uint) string {
+	return strconv.FormatUint(uint64(priceID), 10)
+}
+
+func toRedisPrice(ep models.EventPrice) EventPriceRedis {
+	return EventPriceRedis{
+		ID:              ep.ID,
+		EventID:         ep.EventID,
+		PriceID:         ep.PriceID,
+		Coefficient:     ep.Coefficient,
+		FairCoefficient: ep.FairCoefficient,
+		Active:          ep.Active,
+	}
+}
+
+func fromRedisPrice(sp EventPriceRedis) models.EventPrice {
+	return models.EventPrice{
+		Model:           gorm.Model{ID: sp.ID},
+		EventID:         sp.EventID,
+		PriceID:         sp.PriceID,
+		Coefficient:     sp.Coefficient,
+		FairCoefficient: sp.FairCoefficient,
+		Active:          sp.Active,
+	}
+}
+
+func priceFields(eventPrices []models.EventPrice) (map[string]interface{}, error) {
+	fields := make(map[string]interface{}, len(eventPrices))
+	for _, ep := range eventPrices {
+		data, err := json.Marshal(toRedisPrice(ep))
+		if err != nil {
+			return nil, err
 		}
+		fields[priceField(ep.PriceID)] = data
 	}
+	return fields, nil
+}
 
-	data, err := json.Marshal(simplifiedPrices)
+// SetEventPrices replaces all prices of the event.
+func (r *RedisCache) SetEventPrices(eventID uint, eventPrices []models.EventPrice) error {
+	key := eventPricesKey(eventID)
+
+	fields, err := priceFields(eventPrices)
 	if err != nil {
-		log.Printf("Chexav demid")
 		return err
 	}
 
-	err = r.client.Set(r.ctx, key, data, 5*time.Minute).Err()
+	_, err = r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(r.ctx, key, legacyEventPricesKey(eventID))
+		if len(fields) > 0 {
+			pipe.HSet(r.ctx, key, fields)
+		}
+		return nil
+	})
+	return err
+}
+
+// AddEventPrices adds prices the event does not have yet and leaves the
+// ones it has untouched.
+func (r *RedisCache) AddEventPrices(eventID uint, eventPrices []models.EventPrice) error {
+	if _, err := r.GetEventPrices(eventID); err != nil && !errors.Is(err, ErrPricesNotFound) {
+		return err
+	}
+
+	key := eventPricesKey(eventID)
+
+	fields, err := priceFields(eventPrices)
 	if err != nil {
-		log.Printf("Chexav")
 		return err
 	}
 
-	return nil
+	_, err = r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		for field, data := range fields {
+			pipe.HSetNX(r.ctx, key, field, data)
+		}
+		return nil
+	})
+	return err
 }
 
+// GetEventPrices returns the event's prices ordered by ID, or
+// ErrPricesNotFound when the event has none.
 func (r *RedisCache) GetEventPrices(eventID uint) ([]models.EventPrice, error) {
-	key := fmt.Sprintf("event_prices:%d", eventID)
-	data, err := r.client.Get(r.ctx, key).Result()
+	fields, err := r.client.HGetAll(r.ctx, eventPricesKey(eventID)).Result()
 	if err != nil {
 		return nil, err
 	}
+	if len(fields) == 0 {
+		return r.migrateLegacyPrices(eventID)
+	}
 
-	var simplifiedPrices []EventPriceRedis
-	err = json.Unmarshal([]byte(data), &simplifiedPrices)
+	eventPrices := make([]models.EventPrice, 0, len(fields))
+	for _, data := range fields {
+		var sp EventPriceRedis
+		if err = json.Unmarshal([]byte(data), &sp); err != nil {
+			return nil, err
+		}
+		eventPrices = append(eventPrices, fromRedisPrice(sp))
+	}
+
+	sort.Slice(eventPrices, func(i, j int) bool {
+		return eventPrices[i].ID < eventPrices[j].ID
+	})
+
+	return eventPrices, nil
+}
+
+// UpdateEventPrices applies all coefficient updates of the event at once
+// and returns the coefficient each price had before, keyed by PriceID.
+// Prices the event does not have are left out.
+func (r *RedisCache) UpdateEventPrices(eventID uint, updates []PriceUpdate) (map[uint]float64, error) {
+	var args []interface{}
+	for _, update := range updates {
+		args = append(args, priceField(update.PriceID), update.Coefficient, update.FairCoefficient)
+	}
+
+	result, err := r.runPriceScript(eventID, updateCoefficientsScript, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	values, ok := result.([]interface{})
+	if !ok || len(values)%2 != 0 {
+		return nil, fmt.Errorf("unexpected reply %v updating prices of event %d", result, eventID)
+	}
+
+	previous := make(map[uint]float64, len(values)/2)
+	for i := 0; i < len(values); i += 2 {
+		field, _ := values[i].(string)
+		coefficient, _ := values[i+1].(string)
+
+		priceID, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price field %q of event %d", field, eventID)
+		}
+		old, err := strconv.ParseFloat(coefficient, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid coefficient %q of price %d", coefficient, priceID)
+		}
+		previous[uint(priceID)] = old
+	}
+
+	return previous, nil
+}
+
+// DeactivateEventPrices marks the given prices of the event inactive.
+func (r *RedisCache) DeactivateEventPrices(eventID uint, priceIDs []uint) error {
+	var args []interface{}
+	for _, priceID := range priceIDs {
+		args = append(args, priceField(priceID))
+	}
+	_, err := r.runPriceScript(eventID, deactivateScript, args...)
+	return err
+}
+
+// RemoveEventPrices drops the given prices from the event.
+func (r *RedisCache) RemoveEventPrices(eventID uint, priceIDs []uint) error {
+	if len(priceIDs) == 0 {
+		return nil
+	}
+
+	fields := make([]string, len(priceIDs))
+	for i, priceID := range priceIDs {
+		fields[i] = priceField(priceID)
+	}
+	return r.client.HDel(r.ctx, eventPricesKey(eventID), fields...).Err()
+}
+
+// DeleteEventPrices removes the event's prices in both formats.
+func (r *RedisCache) DeleteEventPrices(eventID uint) error {
+	return r.client.Del(r.ctx, eventPricesKey(eventID), legacyEventPricesKey(eventID)).Err()
+}
+
+// runPriceScript runs a script on the event's hash, migrating the legacy
+// list first when the hash does not exist yet.
+func (r *RedisCache) runPriceScript(eventID uint, script *redis.Script, args ...interface{}) (interface{}, error) {
+	keys := []string{eventPricesKey(eventID)}
+
+	result, err := script.Run(r.ctx, r.client, keys, args...).Result()
+	if err != nil || result != int64(-1) {
+		return result, err
+	}
+
+	if _, err = r.migrateLegacyPrices(eventID); err != nil {
+		return nil, err
+	}
+
+	result, err = script.Run(r.ctx, r.client, keys, args...).Result()
+	if err == nil && result == int64(-1) {
+		return nil, ErrPricesNotFound
+	}
+	return result, err
+}
+
+// migrateLegacyPrices moves prices stored as a JSON list into the event's
+// hash and returns them, or ErrPricesNotFound when there is no list
+// either.
+func (r *RedisCache) migrateLegacyPrices(eventID uint) ([]models.EventPrice, error) {
+	legacyKey := legacyEventPricesKey(eventID)
+
+	data, err := r.client.Get(r.ctx, legacyKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrPricesNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	var simplifiedPrices []EventPriceRedis
+	if err = json.Unmarshal([]byte(data), &simplifiedPrices); err != nil {
+		return nil, err
+	}
+
 	eventPrices := make([]models.EventPrice, len(simplifiedPrices))
 	for i, sp := range simplifiedPrices {
-		eventPrices[i] = models.EventPrice{
-			Model:       gorm.Model{ID: sp.ID},
-			EventID:     sp.EventID,
-			PriceID:     sp.PriceID,
-			Coefficient: sp.Coefficient,
-			Active:      sp.Active,
-		}
+		eventPrices[i] = fromRedisPrice(sp)
+	}
+
+	if err = r.SetEventPrices(eventID, eventPrices); err != nil {
+		return nil, err
 	}
+	log.Printf("Migrated %d prices of event %d from %s to a hash", len(eventPrices), eventID, legacyKey)
 
 	return eventPrices, nil
 }
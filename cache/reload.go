@@ -0,0 +1,160 @@
+package cache
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"log"
+)
+
+// ReloadResult counts what a reload of the static data changed. Events
+// activated in MySQL since the start are not simulated, so they are only
+// listed as pending and picked up by the next start. Events no longer
+// active are listed as removed once, for the generator to retire.
+type ReloadResult struct {
+	ChangedEvents int      `json:"changed_events"`
+	AddedPrices   int      `json:"added_prices"`
+	RemovedPrices int      `json:"removed_prices"`
+	PendingEvents []string `json:"pending_events"`
+	RemovedEvents []string `json:"removed_events"`
+}
+
+// ReloadStaticEventData rereads the running events and their prices from
+// MySQL and swaps them in at once. Only events that gained or lost prices
+// are written to the store, so the live coefficients of the others are
+// kept. Removed events stay in the cache until RetireEvent is called.
+func (c *Cache) ReloadStaticEventData() (ReloadResult, error) {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	snapshot, err := c.loadStaticSnapshot()
+	if err != nil {
+		return ReloadResult{}, err
+	}
+	return c.reloadSnapshot(snapshot), nil
+}
+
+func (c *Cache) reloadSnapshot(snapshot staticSnapshot) ReloadResult {
+	c.mu.RLock()
+	oldEvents, oldLookup := c.eventsMap, c.staticLookup
+	c.mu.RUnlock()
+
+	result := ReloadResult{PendingEvents: []string{}, RemovedEvents: []string{}}
+	events := make(map[uint]models.Event, len(oldEvents))
+	staticLookup := make(map[uint]StaticEventData, len(oldLookup))
+	removed := make(map[uint][]uint)
+
+	for eventID, staticData := range snapshot.staticLookup {
+		if _, running := oldLookup[eventID]; !running {
+			result.PendingEvents = append(result.PendingEvents, staticData.EventCode)
+		}
+	}
+
+	for eventID, oldData := range oldLookup {
+		staticData, active := snapshot.staticLookup[eventID]
+		if !active {
+			c.mu.Lock()
+			if !c.removed[eventID] {
+				c.removed[eventID] = true
+				result.RemovedEvents = append(result.RemovedEvents, oldData.EventCode)
+			}
+			c.mu.Unlock()
+			events[eventID] = oldEvents[eventID]
+			staticLookup[eventID] = oldData
+			continue
+		}
+		events[eventID] = snapshot.events[eventID]
+		staticLookup[eventID] = staticData
+
+		c.mu.Lock()
+		delete(c.removed, eventID)
+		c.mu.Unlock()
+
+		changed := eventChanged(oldEvents[eventID], snapshot.events[eventID]) || !sameStaticData(oldData, staticData)
+
+		var added []uint
+		for priceID, relation := range staticData.PriceRelations {
+			oldRelation, known := oldData.PriceRelations[priceID]
+			switch {
+			case !known:
+				added = append(added, priceID)
+			case oldRelation != relation:
+				changed = true
+			}
+		}
+		for priceID := range oldData.PriceRelations {
+			if _, kept := staticData.PriceRelations[priceID]; !kept {
+				removed[eventID] = append(removed[eventID], priceID)
+			}
+		}
+
+		if len(added) > 0 {
+			c.mu.Lock()
+			delete(c.unpriced, eventID)
+			c.mu.Unlock()
+			c.storePrices(eventID, pricesWithIDs(snapshot.prices[eventID], added), c.store.AddEventPrices)
+		}
+		if changed || len(added) > 0 || len(removed[eventID]) > 0 {
+			result.ChangedEvents++
+		}
+		result.AddedPrices += len(added)
+		result.RemovedPrices += len(removed[eventID])
+	}
+
+	c.mu.Lock()
+	c.eventsMap = events
+	c.staticLookup = staticLookup
+	c.mu.Unlock()
+
+	for eventID, priceIDs := range removed {
+		if err := c.store.RemoveEventPrices(eventID, priceIDs); err != nil {
+			log.Printf("Error removing prices of event %d: %v", eventID, err)
+		}
+	}
+
+	return result
+}
+
+func (c *Cache) storePrices(eventID uint, eventPrices []models.EventPrice, store func(uint, []models.EventPrice) error) {
+	if err := store(eventID, eventPrices); err != nil {
+		log.Printf("Error storing event prices for event %d: %v", eventID, err)
+	}
+}
+
+func pricesWithIDs(eventPrices []models.EventPrice, priceIDs []uint) []models.EventPrice {
+	wanted := make(map[uint]bool, len(priceIDs))
+	for _, priceID := range priceIDs {
+		wanted[priceID] = true
+	}
+
+	var filtered []models.EventPrice
+	for _, ep := range eventPrices {
+		if wanted[ep.PriceID] {
+			filtered = append(filtered, ep)
+		}
+	}
+	return filtered
+}
+
+// eventChanged reports whether anything the pricing reads from the event
+// row or its teams changed.
+func eventChanged(old, new models.Event) bool {
+	if !old.UpdatedAt.Equal(new.UpdatedAt) || old.Competition.Country.Sport.Code != new.Competition.Country.Sport.Code {
+		return true
+	}
+	if old.HomeTeamID != new.HomeTeamID || old.AwayTeamID != new.AwayTeamID || len(old.Teams) != len(new.Teams) {
+		return true
+	}
+	for i := range old.Teams {
+		if old.Teams[i].ID != new.Teams[i].ID || old.Teams[i].Name != new.Teams[i].Name || old.Teams[i].Rating != new.Teams[i].Rating {
+			return true
+		}
+	}
+	return false
+}
+
+func sameStaticData(a, b StaticEventData) bool {
+	return a.EventName == b.EventName &&
+		a.EventCode == b.EventCode &&
+		a.CompetitionName == b.CompetitionName &&
+		a.CountryName == b.CountryName &&
+		a.SportName == b.SportName
+}
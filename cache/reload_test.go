@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"fmt"
+	"github.com/VaheMuradyan/Live2/db/models"
+	"gorm.io/gorm"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func reloadEvent(id uint, code, name string) models.Event {
+	return models.Event{Model: gorm.Model{ID: id}, Code: code, Name: name}
+}
+
+func reloadPrice(eventID, priceID uint, coefficient float64) models.EventPrice {
+	return models.EventPrice{
+		Model:       gorm.Model{ID: eventID*100 + priceID},
+		EventID:     eventID,
+		PriceID:     priceID,
+		Coefficient: coefficient,
+		Active:      true,
+		Price:       models.Price{Model: gorm.Model{ID: priceID}, Code: fmt.Sprintf("P%d", priceID), Market: models.Market{Code: "M"}},
+	}
+}
+
+func storedPriceIDs(t *testing.T, c *Cache, eventID uint) []uint {
+	t.Helper()
+
+	var priceIDs []uint
+	for _, eventPrice := range c.GetEventPrices(eventID, false) {
+		priceIDs = append(priceIDs, eventPrice.PriceID)
+	}
+	sort.Slice(priceIDs, func(i, j int) bool { return priceIDs[i] < priceIDs[j] })
+	return priceIDs
+}
+
+func TestReloadDiff(t *testing.T) {
+	c := NewCacheWithStore(nil, NewMemoryPriceStore())
+	c.LoadEvents(
+		[]models.Event{reloadEvent(1, "MA", "A"), reloadEvent(2, "MB", "B"), reloadEvent(3, "MC", "C"), reloadEvent(5, "ME", "E")},
+		[]models.EventPrice{reloadPrice(1, 1, 2), reloadPrice(1, 2, 3), reloadPrice(2, 1, 2), reloadPrice(3, 1, 2), reloadPrice(5, 1, 2)},
+	)
+	if _, err := c.UpdateEventPrices(1, []PriceUpdate{{PriceID: 1, Coefficient: 1.7, FairCoefficient: 1.6}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// MA loses price 2 and gains price 3, MB is renamed, MC is deactivated,
+	// MD is activated and ME is unchanged.
+	snapshot := newStaticSnapshot(
+		[]models.Event{reloadEvent(1, "MA", "A"), reloadEvent(2, "MB", "B2"), reloadEvent(4, "MD", "D"), reloadEvent(5, "ME", "E")},
+		[]models.EventPrice{reloadPrice(1, 1, 2), reloadPrice(1, 3, 4), reloadPrice(2, 1, 2), reloadPrice(4, 1, 2), reloadPrice(5, 1, 2)},
+	)
+
+	result := c.reloadSnapshot(snapshot)
+	want := ReloadResult{ChangedEvents: 2, AddedPrices: 1, RemovedPrices: 1, PendingEvents: []string{"MD"}, RemovedEvents: []string{"MC"}}
+	if !reflect.DeepEqual(result, want) {
+		t.Fatalf("got %+v, want %+v", result, want)
+	}
+
+	if got := storedPriceIDs(t, c, 1); !reflect.DeepEqual(got, []uint{1, 3}) {
+		t.Fatalf("MA stores prices %v, want [1 3]", got)
+	}
+	if eventPrice, err := c.GetEventPriceByCode("MA", "P1"); err != nil || eventPrice.Coefficient != 1.7 {
+		t.Fatalf("MA kept coefficient %v (%v), want the live 1.7", eventPrice.Coefficient, err)
+	}
+	if event, exists := c.GetEvent(2); !exists || event.Name != "B2" {
+		t.Fatalf("MB is %+v, want it renamed", event)
+	}
+	if _, exists := c.GetEvent(3); !exists {
+		t.Fatal("MC was dropped before it was retired")
+	}
+	if _, exists := c.GetEvent(4); exists {
+		t.Fatal("MD became live without a simulation")
+	}
+
+	result = c.reloadSnapshot(snapshot)
+	want = ReloadResult{PendingEvents: []string{"MD"}, RemovedEvents: []string{}}
+	if !reflect.DeepEqual(result, want) {
+		t.Fatalf("second reload got %+v, want %+v", result, want)
+	}
+
+	c.EndEvent(3)
+	if result = c.reloadSnapshot(snapshot); len(result.RemovedEvents) != 0 {
+		t.Fatalf("retired event reported again: %+v", result)
+	}
+}
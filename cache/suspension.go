@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"time"
+)
+
+type Suspension struct {
+	Reason string    `json:"reason"`
+	Since  time.Time `json:"since"`
+	Until  time.Time `json:"until"`
+}
+
+// SuspendEvent takes every market of the event off the board for the window
+// from now. An open suspension is only ever extended, never shortened, and
+// at most to the window counted from when it opened, so incidents arriving
+// faster than their window cannot keep the event suspended. The effective
+// end of the suspension is returned.
+func (c *Cache) SuspendEvent(eventID uint, reason string, now time.Time, window time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	suspension := Suspension{Reason: reason, Since: now, Until: now.Add(window)}
+	if current, exists := c.suspensions[eventID]; exists {
+		suspension.Since = current.Since
+		if limit := current.Since.Add(window); suspension.Until.After(limit) {
+			suspension.Until = limit
+		}
+		if current.Until.After(suspension.Until) {
+			suspension.Until = current.Until
+		}
+	}
+	c.suspensions[eventID] = suspension
+
+	return suspension.Until
+}
+
+func (c *Cache) ReopenEvent(eventID uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.suspensions, eventID)
+}
+
+func (c *Cache) GetSuspension(eventID uint) (Suspension, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	suspension, exists := c.suspensions[eventID]
+	return suspension, exists
+}
+
+func (c *Cache) PriceStatus(eventPrice models.EventPrice) string {
+	if !eventPrice.Active {
+		return models.PriceStatusClosed
+	}
+	if _, suspended := c.GetSuspension(eventPrice.EventID); suspended {
+		return models.PriceStatusSuspended
+	}
+	return models.PriceStatusOpen
+}
@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"testing"
+	"time"
+)
+
+func TestSuspensionReopensDuringFastPointStream(t *testing.T) {
+	c := NewCacheWithStore(nil, NewMemoryPriceStore())
+	const window = 300 * time.Millisecond
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// A point every 100ms, three times as fast as the window.
+	var until time.Time
+	for now := start; now.Before(start.Add(time.Second)); now = now.Add(100 * time.Millisecond) {
+		if suspension, open := c.GetSuspension(1); open && !now.Before(suspension.Until) {
+			c.ReopenEvent(1)
+		}
+		until = c.SuspendEvent(1, models.IncidentPoint, now, window)
+
+		suspension, _ := c.GetSuspension(1)
+		if until.Sub(suspension.Since) > window {
+			t.Fatalf("point at %v pushed the suspension opened at %v out to %v", now.Sub(start), suspension.Since.Sub(start), until.Sub(start))
+		}
+	}
+
+	// Reopened at 300ms, 600ms and 900ms, so the last window opened at 900ms.
+	if want := start.Add(900*time.Millisecond + window); !until.Equal(want) {
+		t.Fatalf("last suspension ends at %v, want %v", until.Sub(start), want.Sub(start))
+	}
+}
+
+func TestSuspensionExtendsButNeverShortens(t *testing.T) {
+	c := NewCacheWithStore(nil, NewMemoryPriceStore())
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		at     time.Duration
+		window time.Duration
+		want   time.Duration
+	}{
+		{"opens", 0, 5 * time.Second, 5 * time.Second},
+		{"shorter window keeps the end", time.Second, 3 * time.Second, 5 * time.Second},
+		{"longer window extends from the opening", 2 * time.Second, 8 * time.Second, 8 * time.Second},
+	}
+
+	for _, tt := range tests {
+		until := c.SuspendEvent(1, models.IncidentGoal, start.Add(tt.at), tt.window)
+		if want := start.Add(tt.want); !until.Equal(want) {
+			t.Fatalf("%s: ends at %v, want %v", tt.name, until.Sub(start), tt.want)
+		}
+	}
+}
@@ -67,7 +67,7 @@ func (s *CentrifugoClient) Close() {
 	s.cfConn.Close()
 }
 
-func (s *CentrifugoClient) SendToCentrifugo(eventPrice models.EventPrice) error {
+func (s *CentrifugoClient) SendToCentrifugo(eventPrice models.EventPrice, oldCoefficient float64, score models.ScoreSnapshot, status string) error {
 	price := eventPrice.Price
 	market := price.Market
 	marketCollection := market.MarketCollection
@@ -85,10 +85,19 @@ func (s *CentrifugoClient) SendToCentrifugo(eventPrice models.EventPrice) error
 		"market_collection_code": marketCollection.Code,
 		"price":                  price.Name,
 		"new_coefficient":        eventPrice.Coefficient,
-		"old_coefficient":        float64(5),
+		"old_coefficient":        oldCoefficient,
 		"timestamp":              time.Now().Format(time.RFC3339),
 		"coefficient_id":         eventPrice.ID,
 		"active":                 eventPrice.Active,
+		"status":                 status,
+		"score":                  fmt.Sprintf("%d-%d", score.Team1Score, score.Team2Score),
+		"minute":                 score.Minute,
+		"added_time":             score.AddedTime,
+		"period":                 score.Period,
+	}
+
+	if score.Tennis != nil {
+		data["tennis"] = tennisPayload(score)
 	}
 
 	lower := strings.ToLower(event.Name)
@@ -112,3 +121,39 @@ func (s *CentrifugoClient) SendToCentrifugo(eventPrice models.EventPrice) error
 
 	return err
 }
+
+// tennisPayload describes a tennis score the way it is shown on a
+// scoreboard: sets, games of every set and the points of the current game.
+func tennisPayload(score models.ScoreSnapshot) map[string]interface{} {
+	games := make([]string, len(score.Periods))
+	for i, set := range score.Periods {
+		games[i] = fmt.Sprintf("%d-%d", set.Team1, set.Team2)
+	}
+
+	points := fmt.Sprintf("%d-%d", score.Tennis.Team1Points, score.Tennis.Team2Points)
+	if !score.Tennis.TieBreak {
+		points = gamePoints(score.Tennis.Team1Points, score.Tennis.Team2Points)
+	}
+
+	return map[string]interface{}{
+		"sets":      fmt.Sprintf("%d-%d", score.Team1Score, score.Team2Score),
+		"games":     games,
+		"points":    points,
+		"server":    score.Tennis.Server,
+		"tie_break": score.Tennis.TieBreak,
+	}
+}
+
+func gamePoints(points1, points2 int) string {
+	calls := []string{"0", "15", "30", "40"}
+
+	switch {
+	case points1 >= 3 && points2 >= 3 && points1 == points2:
+		return "40-40"
+	case points1 >= 3 && points2 >= 3 && points1 > points2:
+		return "AD-40"
+	case points1 >= 3 && points2 >= 3:
+		return "40-AD"
+	}
+	return calls[min(points1, 3)] + "-" + calls[min(points2, 3)]
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"fmt"
+	"github.com/VaheMuradyan/Live2/db/models"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -60,3 +61,17 @@ func Connect() *gorm.DB {
 
 	return db
 }
+
+func Migrate(db *gorm.DB) {
+	err := db.AutoMigrate(
+		&models.Event{},
+		&models.MarketCollection{},
+		&models.Market{},
+		&models.EventPrice{},
+		&models.Settlement{},
+		&models.Bet{},
+	)
+	if err != nil {
+		fmt.Printf("failed to migrate database: %v\n", err)
+	}
+}
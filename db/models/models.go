@@ -45,6 +45,7 @@ type MarketCollection struct {
 	gorm.Model
 	Name    string   `gorm:"unique"`
 	Code    string   `gorm:"unique"`
+	Margin  float64  `gorm:"type:decimal(5,4);default:0"`
 	Markets []Market `gorm:"foreignKey:MarketCollectionID"`
 	Events  []Event  `gorm:"many2many:event_market_collections;"`
 }
@@ -57,6 +58,8 @@ type Market struct {
 	MarketCollection   MarketCollection `gorm:"foreignKey:MarketCollectionID" `
 	Prices             []Price          `gorm:"foreignKey:MarketID"`
 	Active             bool             `gorm:"default:false"`
+	Margin             float64          `gorm:"type:decimal(5,4);default:0"`
+	MaxStake           float64          `gorm:"type:decimal(12,2);default:0"`
 }
 
 type Price struct {
@@ -71,18 +74,21 @@ type Price struct {
 
 type EventPrice struct {
 	gorm.Model
-	EventID     uint    `gorm:"index"`
-	Event       Event   `gorm:"foreignKey:EventID"`
-	PriceID     uint    `gorm:"index"`
-	Price       Price   `gorm:"foreignKey:PriceID"`
-	Coefficient float64 `gorm:"type:decimal(9,4);"`
-	Active      bool    `gorm:"default:true"`
+	EventID         uint    `gorm:"index"`
+	Event           Event   `gorm:"foreignKey:EventID"`
+	PriceID         uint    `gorm:"index"`
+	Price           Price   `gorm:"foreignKey:PriceID"`
+	Coefficient     float64 `gorm:"type:decimal(9,4);"`
+	FairCoefficient float64 `gorm:"type:decimal(9,4);"`
+	Active          bool    `gorm:"default:true"`
 }
 
 type Event struct {
 	gorm.Model
 	Name              string
 	CompetitionID     uint
+	HomeTeamID        uint
+	AwayTeamID        uint
 	Competition       Competition        `gorm:"foreignKey:CompetitionID"`
 	Teams             []Team             `gorm:"many2many:event_teams;"`
 	MarketCollections []MarketCollection `gorm:"many2many:event_market_collections;"`
@@ -91,9 +97,38 @@ type Event struct {
 	Code              string             `gorm:"unique"`
 }
 
+type Settlement struct {
+	gorm.Model
+	EventID      uint       `gorm:"index"`
+	EventPriceID uint       `gorm:"uniqueIndex"`
+	EventPrice   EventPrice `gorm:"foreignKey:EventPriceID"`
+	PriceID      uint       `gorm:"index"`
+	Outcome      string     `gorm:"size:16"`
+	Coefficient  float64    `gorm:"type:decimal(9,4);"`
+	Team1Score   int
+	Team2Score   int
+}
+
+type Bet struct {
+	gorm.Model
+	EventID              uint       `gorm:"index"`
+	EventPriceID         uint       `gorm:"index"`
+	EventPrice           EventPrice `gorm:"foreignKey:EventPriceID"`
+	PriceID              uint       `gorm:"index"`
+	Stake                float64    `gorm:"type:decimal(12,2);"`
+	Coefficient          float64    `gorm:"type:decimal(9,4);"`
+	RequestedCoefficient float64    `gorm:"type:decimal(9,4);"`
+	Settled              bool       `gorm:"index;default:false"`
+	Outcome              string     `gorm:"size:16"`
+}
+
 type RequestData struct {
-	EventCodes  []string `json:"event_codes"`
-	MarketCodes []string `json:"market_codes"`
+	EventCodes       []string          `json:"event_codes"`
+	MarketCodes      []string          `json:"market_codes"`
+	Seed             int64             `json:"seed"`
+	EventSeeds       map[string]int64  `json:"event_seeds"`
+	SecondsPerMinute float64           `json:"seconds_per_minute"`
+	Sources          map[string]string `json:"sources"`
 }
 
 type GetEventListResponse struct {
@@ -102,9 +137,196 @@ type GetEventListResponse struct {
 	EventCode string `json:"event_code"`
 }
 
+type EventResultResponse struct {
+	MarketCode  string  `json:"market_code"`
+	PriceCode   string  `json:"price_code"`
+	PriceName   string  `json:"price_name"`
+	Coefficient float64 `json:"coefficient"`
+	Outcome     string  `json:"outcome"`
+}
+
+type PlaceBetRequest struct {
+	EventCode   string  `json:"event_code"`
+	PriceCode   string  `json:"price_code"`
+	Stake       float64 `json:"stake"`
+	Coefficient float64 `json:"coefficient"`
+}
+
+type EventSpeedRequest struct {
+	Factor float64 `json:"factor"`
+}
+
+type InjectIncidentRequest struct {
+	Type string `json:"type"`
+	Team int    `json:"team"`
+}
+
+type PlaceBetResponse struct {
+	BetID       uint    `json:"bet_id"`
+	EventCode   string  `json:"event_code"`
+	PriceCode   string  `json:"price_code"`
+	Stake       float64 `json:"stake"`
+	Coefficient float64 `json:"coefficient"`
+}
+
+type PriceExposure struct {
+	PriceCode   string  `json:"price_code"`
+	PriceName   string  `json:"price_name"`
+	Stakes      float64 `json:"stakes"`
+	Liability   float64 `json:"liability"`
+	NetExposure float64 `json:"net_exposure"`
+}
+
+type MarketExposure struct {
+	MarketCode  string          `json:"market_code"`
+	Stakes      float64         `json:"stakes"`
+	MaxExposure float64         `json:"max_exposure"`
+	Prices      []PriceExposure `json:"prices"`
+}
+
+type EventExposureResponse struct {
+	EventCode        string           `json:"event_code"`
+	Suspended        bool             `json:"suspended"`
+	SuspensionReason string           `json:"suspension_reason,omitempty"`
+	Stakes           float64          `json:"stakes"`
+	MaxExposure      float64          `json:"max_exposure"`
+	Markets          []MarketExposure `json:"markets"`
+}
+
+type EventPriceResponse struct {
+	MarketCode  string  `json:"market_code"`
+	PriceCode   string  `json:"price_code"`
+	PriceName   string  `json:"price_name"`
+	Coefficient float64 `json:"coefficient"`
+	Status      string  `json:"status"`
+}
+
+type EventPricesResponse struct {
+	EventCode        string               `json:"event_code"`
+	Suspended        bool                 `json:"suspended"`
+	SuspensionReason string               `json:"suspension_reason,omitempty"`
+	Prices           []EventPriceResponse `json:"prices"`
+}
+
+// ScoreSnapshot is the state of an event at one moment. It is
+// deliberately one shape for every sport rather than one per sport: it
+// travels as a single message type over RabbitMQ, replays and Centrifugo,
+// and every market calculator reads it the same way, so a sport leaves the
+// fields it does not keep at their zero values. Team1Score and Team2Score
+// hold goals or points, or sets won in tennis.
 type ScoreSnapshot struct {
 	EventID    uint
+	Sport      string
 	Team1Score int
 	Team2Score int
 	Total      int
+	Minute     int
+	AddedTime  int
+	Period     string
+
+	// Football; hockey keeps the goals and any cards injected into it.
+	Team1RedCards      int
+	Team2RedCards      int
+	Team1YellowCards   int
+	Team2YellowCards   int
+	Team1Corners       int
+	Team2Corners       int
+	HalfTimeTeam1Score int
+	HalfTimeTeam2Score int
+	Goals              []Goal
+
+	// Periods holds the score of every quarter, period or set played so far
+	// in basketball, hockey and tennis, and Tennis the state of the current
+	// set and game.
+	Periods []PeriodScore
+	Tennis  *TennisScore
+}
+
+type PeriodScore struct {
+	Team1 int
+	Team2 int
+}
+
+// TennisScore tracks the player serving, the points of the game or
+// tie-break in progress and the winner of every game played so far.
+type TennisScore struct {
+	Server      int
+	TieBreak    bool
+	Team1Points int
+	Team2Points int
+	GameWinners []int
+}
+
+// Goal records the side a goal counted for and the minute it was scored.
+type Goal struct {
+	Team   int
+	Minute int
+}
+
+const (
+	PeriodFirstHalf  = "1H"
+	PeriodHalfTime   = "HT"
+	PeriodSecondHalf = "2H"
+	PeriodFullTime   = "FT"
+
+	PeriodQuarter1 = "Q1"
+	PeriodQuarter2 = "Q2"
+	PeriodQuarter3 = "Q3"
+	PeriodQuarter4 = "Q4"
+	PeriodOvertime = "OT"
+
+	PeriodHockey1  = "P1"
+	PeriodHockey2  = "P2"
+	PeriodHockey3  = "P3"
+	PeriodShootout = "SO"
+
+	PeriodSet1 = "S1"
+	PeriodSet2 = "S2"
+	PeriodSet3 = "S3"
+)
+
+// Incident is a single match event. Team is 1 or 2 for the side the incident
+// belongs to; an own goal belongs to the team that conceded it by its own
+// player, so the opponent's score goes up.
+type Incident struct {
+	EventID   uint
+	Type      string
+	Team      int
+	Minute    int
+	AddedTime int
+	Period    string
+}
+
+const (
+	IncidentGoal           = "goal"
+	IncidentOwnGoal        = "own_goal"
+	IncidentPenaltyAwarded = "penalty_awarded"
+	IncidentPenaltyScored  = "penalty_scored"
+	IncidentPenaltyMissed  = "penalty_missed"
+	IncidentYellowCard     = "yellow_card"
+	IncidentRedCard        = "red_card"
+	IncidentCorner         = "corner"
+	IncidentSubstitution   = "substitution"
+	IncidentVARReview      = "var_review"
+	IncidentGoalCancelled  = "goal_cancelled"
+	IncidentPoint          = "point"
+)
+
+// QueueMessage is what the simulation publishes to an event's queue: either
+// a full score snapshot or a single incident.
+type QueueMessage struct {
+	Type     string         `json:"type"`
+	Snapshot *ScoreSnapshot `json:"snapshot,omitempty"`
+	Incident *Incident      `json:"incident,omitempty"`
 }
+
+const (
+	PriceStatusOpen      = "open"
+	PriceStatusSuspended = "suspended"
+	PriceStatusClosed    = "closed"
+)
+
+const (
+	MessageTypeSnapshot = "snapshot"
+	MessageTypeIncident = "incident"
+)
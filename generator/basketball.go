@@ -0,0 +1,160 @@
+package generator
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"github.com/VaheMuradyan/Live2/generator/markets"
+	"math"
+	"math/rand"
+)
+
+const (
+	pointsPerPlay       = 2.2
+	runChancePerMinute  = 0.08
+	runMinutes          = 3
+	runBoost            = 1.6
+	runDrag             = 0.6
+	threePointPlayShare = 0.3
+	freeThrowPlayShare  = 0.1
+)
+
+var quarters = []string{models.PeriodQuarter1, models.PeriodQuarter2, models.PeriodQuarter3, models.PeriodQuarter4}
+
+// basketballGame plays four quarters a minute at a time, with overtime
+// while the scores are level. Scoring comes in plays of one to three
+// points, and now and then one side goes on a run that lifts its scoring
+// and holds back the other side's for a few minutes.
+type basketballGame struct {
+	rng     *rand.Rand
+	model   markets.GoalModel
+	quarter int
+	minute  int
+	end     int
+	runTeam int
+	runLeft int
+	done    bool
+}
+
+func newBasketballGame(rng *rand.Rand, model markets.GoalModel) *basketballGame {
+	return &basketballGame{
+		rng:   rng,
+		model: model,
+		end:   markets.QuarterMinutes,
+	}
+}
+
+func (b *basketballGame) start(score *models.ScoreSnapshot) {
+	score.Sport = markets.SportBasketball
+	score.Period = models.PeriodQuarter1
+	score.Periods = []models.PeriodScore{{}}
+}
+
+func (b *basketballGame) tick(score *models.ScoreSnapshot) ([]models.Incident, bool) {
+	if b.done {
+		return nil, false
+	}
+
+	b.minute++
+	score.Minute = b.minute
+	b.updateRun()
+
+	for _, team := range []int{1, 2} {
+		rate := b.model.HomeRate
+		if team == 2 {
+			rate = b.model.AwayRate
+		}
+		rate = rate / markets.BasketballMinutes * b.runFactor(team)
+
+		for plays := poissonDraw(b.rng, rate/pointsPerPlay); plays > 0; plays-- {
+			addPoints(score, team, b.playPoints())
+		}
+	}
+
+	if b.minute < b.end {
+		return nil, false
+	}
+
+	b.quarter++
+	switch {
+	case b.quarter < len(quarters):
+		score.Period = quarters[b.quarter]
+		b.end += markets.QuarterMinutes
+	case score.Team1Score == score.Team2Score:
+		score.Period = models.PeriodOvertime
+		b.end += markets.OvertimeMinutes
+	default:
+		score.Period = models.PeriodFullTime
+		b.done = true
+		return nil, true
+	}
+
+	score.Periods = append(score.Periods, models.PeriodScore{})
+	return nil, true
+}
+
+func (b *basketballGame) updateRun() {
+	if b.runLeft > 0 {
+		b.runLeft--
+		return
+	}
+
+	b.runTeam = 0
+	if b.rng.Float64() < runChancePerMinute {
+		b.runTeam = 1
+		if b.rng.Float64() >= b.model.HomeShare() {
+			b.runTeam = 2
+		}
+		b.runLeft = runMinutes
+	}
+}
+
+func (b *basketballGame) runFactor(team int) float64 {
+	switch b.runTeam {
+	case 0:
+		return 1
+	case team:
+		return runBoost
+	}
+	return runDrag
+}
+
+func (b *basketballGame) playPoints() int {
+	draw := b.rng.Float64()
+	switch {
+	case draw < freeThrowPlayShare:
+		return 1
+	case draw < freeThrowPlayShare+threePointPlayShare:
+		return 3
+	}
+	return 2
+}
+
+func (b *basketballGame) snapshotDue() bool {
+	return !b.done
+}
+
+func (b *basketballGame) finished() bool {
+	return b.done
+}
+
+func addPoints(score *models.ScoreSnapshot, team, points int) {
+	period := &score.Periods[len(score.Periods)-1]
+	if team == 1 {
+		score.Team1Score += points
+		period.Team1 += points
+	} else {
+		score.Team2Score += points
+		period.Team2 += points
+	}
+	score.Total += points
+}
+
+// poissonDraw draws the number of events of a Poisson process with the
+// given mean.
+func poissonDraw(rng *rand.Rand, mean float64) int {
+	limit := math.Exp(-mean)
+	count := 0
+	for p := rng.Float64(); p > limit; p *= rng.Float64() {
+		count++
+	}
+	return count
+}
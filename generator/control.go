@@ -0,0 +1,179 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"github.com/VaheMuradyan/Live2/db/models"
+	"github.com/VaheMuradyan/Live2/generator/markets"
+	"time"
+)
+
+const (
+	commandPause  = "pause"
+	commandResume = "resume"
+	commandStop   = "stop"
+	commandSpeed  = "speed"
+	commandInject = "inject"
+
+	maxSpeedFactor = 100
+)
+
+var (
+	ErrEventNotRunning = errors.New("event is not running")
+	ErrInvalidCommand  = errors.New("invalid event command")
+)
+
+var injectableIncidents = map[string]bool{
+	models.IncidentGoal:       true,
+	models.IncidentYellowCard: true,
+	models.IncidentRedCard:    true,
+}
+
+// eventCommand is sent to a running event's goroutine, which answers on
+// reply once it has applied the command.
+type eventCommand struct {
+	action   string
+	factor   float64
+	incident models.Incident
+	reply    chan error
+}
+
+// eventControl is the control channel of a running event. done is closed
+// when the event's goroutine returns.
+type eventControl struct {
+	commands chan eventCommand
+	done     chan struct{}
+}
+
+func (g *Generator) PauseEvent(eventCode string) error {
+	return g.sendCommand(eventCode, eventCommand{action: commandPause})
+}
+
+func (g *Generator) ResumeEvent(eventCode string) error {
+	return g.sendCommand(eventCode, eventCommand{action: commandResume})
+}
+
+// StopEvent ends a single event without settling it.
+func (g *Generator) StopEvent(eventCode string) error {
+	return g.sendCommand(eventCode, eventCommand{action: commandStop})
+}
+
+// SetEventSpeed runs the event factor times faster than the speed it was
+// started with.
+func (g *Generator) SetEventSpeed(eventCode string, factor float64) error {
+	if factor <= 0 || factor > maxSpeedFactor {
+		return fmt.Errorf("%w: speed factor must be above 0 and at most %d", ErrInvalidCommand, maxSpeedFactor)
+	}
+	return g.sendCommand(eventCode, eventCommand{action: commandSpeed, factor: factor})
+}
+
+// InjectIncident makes a goal or card for the team happen at the event's
+// current minute, as if the simulation had produced it.
+func (g *Generator) InjectIncident(eventCode, incidentType string, team int) error {
+	if !injectableIncidents[incidentType] {
+		return fmt.Errorf("%w: incident type %q cannot be injected", ErrInvalidCommand, incidentType)
+	}
+	if team != 1 && team != 2 {
+		return fmt.Errorf("%w: team must be 1 or 2", ErrInvalidCommand)
+	}
+	return g.sendCommand(eventCode, eventCommand{action: commandInject, incident: models.Incident{Type: incidentType, Team: team}})
+}
+
+func (g *Generator) sendCommand(eventCode string, command eventCommand) error {
+	event, exists := g.cache.GetEventByCode(eventCode)
+	if !exists {
+		return ErrEventNotRunning
+	}
+
+	g.controlsMu.Lock()
+	control, running := g.controls[event.ID]
+	g.controlsMu.Unlock()
+	if !running {
+		return ErrEventNotRunning
+	}
+
+	command.reply = make(chan error, 1)
+
+	select {
+	case control.commands <- command:
+	case <-control.done:
+		return ErrEventNotRunning
+	}
+
+	select {
+	case err := <-command.reply:
+		return err
+	case <-control.done:
+		return ErrEventNotRunning
+	}
+}
+
+func (g *Generator) addControl(eventID uint) *eventControl {
+	control := &eventControl{
+		commands: make(chan eventCommand),
+		done:     make(chan struct{}),
+	}
+
+	g.controlsMu.Lock()
+	g.controls[eventID] = control
+	g.controlsMu.Unlock()
+
+	return control
+}
+
+func (g *Generator) removeControl(eventID uint, control *eventControl) {
+	g.controlsMu.Lock()
+	delete(g.controls, eventID)
+	g.controlsMu.Unlock()
+
+	close(control.done)
+}
+
+// eventRun is the state of a running event that commands change.
+type eventRun struct {
+	ticker   *time.Ticker
+	interval time.Duration
+	paused   bool
+	stopped  bool
+}
+
+func (r *eventRun) ticks() <-chan time.Time {
+	if r.paused {
+		return nil
+	}
+	return r.ticker.C
+}
+
+// applyCommand carries out a command on the event and returns the incident
+// to publish when one was injected.
+func (g *Generator) applyCommand(run *eventRun, score *models.ScoreSnapshot, command eventCommand) (*models.Incident, error) {
+	switch command.action {
+	case commandPause:
+		run.paused = true
+	case commandResume:
+		run.paused = false
+	case commandStop:
+		run.stopped = true
+	case commandSpeed:
+		run.ticker.Reset(time.Duration(float64(run.interval) / command.factor))
+	case commandInject:
+		if sport := g.eventSport(score.EventID); sport != markets.SportFootball && sport != markets.SportHockey {
+			return nil, fmt.Errorf("%w: incidents cannot be injected into %s events", ErrInvalidCommand, sport)
+		}
+		if score.Period == models.PeriodHalfTime || score.Period == models.PeriodFullTime {
+			return nil, fmt.Errorf("%w: event is not in play", ErrInvalidCommand)
+		}
+
+		incident := command.incident
+		incident.EventID = score.EventID
+		incident.Minute = score.Minute
+		incident.AddedTime = score.AddedTime
+		incident.Period = score.Period
+
+		applyIncident(score, incident)
+		return &incident, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown action %q", ErrInvalidCommand, command.action)
+	}
+	return nil, nil
+}
@@ -1,6 +1,7 @@
 package generator
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/VaheMuradyan/Live2/db/models"
@@ -11,29 +12,78 @@ import (
 	"time"
 )
 
-func (g *Generator) startEventsSimulation() {
+const priceUpdateMinutes = 5
+
+// startEventsSimulation runs every event until it finishes or ctx is
+// cancelled and returns the final scores of the events that finished.
+func (g *Generator) startEventsSimulation(ctx context.Context, data models.RequestData) <-chan models.ScoreSnapshot {
 	scores := g.cache.GetAllScoreSnapshotsForSimulation()
 
+	seed := data.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	log.Printf("Starting simulation with seed %d", seed)
+
 	num := len(scores)
-	stopChan := make(chan bool, num)
+	finalScores := make(chan models.ScoreSnapshot, num)
 
 	var wg sync.WaitGroup
 	wg.Add(num)
 
 	for _, score := range scores {
-		go g.startEvent(score, stopChan, &wg)
+		rng := rand.New(rand.NewSource(g.eventSeed(seed, data.EventSeeds, score.EventID)))
+		simulator := g.newSimulator(score.EventID, rng, g.eventReplay(data.Sources, score.EventID))
+		go g.startEvent(ctx, score, simulator, minuteInterval(data.SecondsPerMinute), finalScores, &wg)
 	}
 
-	time.Sleep(55 * time.Second)
-	for i := 0; i < num; i++ {
-		stopChan <- true
+	wg.Wait()
+	close(finalScores)
+
+	return finalScores
+}
+
+// eventSeed picks the seed requested for the event's code, falling back to
+// one derived from the run seed so every event gets its own stream.
+func (g *Generator) eventSeed(seed int64, eventSeeds map[string]int64, eventID uint) int64 {
+	if event, exists := g.cache.GetEvent(eventID); exists {
+		if eventSeed, ok := eventSeeds[event.Code]; ok {
+			return eventSeed
+		}
 	}
-	g.stopChan <- true
+	return seed + int64(eventID)
+}
 
-	wg.Wait()
+// eventReplay loads the timeline requested for the event's code, or
+// returns nil when the event should be simulated at random.
+func (g *Generator) eventReplay(sources map[string]string, eventID uint) *Replay {
+	event, exists := g.cache.GetEvent(eventID)
+	if !exists {
+		return nil
+	}
+
+	path, err := ParseSource(sources[event.Code])
+	if err != nil || path == "" {
+		return nil
+	}
+
+	replay, err := LoadReplay(path, event.Code)
+	if err != nil {
+		log.Printf("Error loading replay for event %s, simulating at random: %v", event.Code, err)
+		return nil
+	}
+	return replay
+}
+
+func (g *Generator) settleEvents(finalScores <-chan models.ScoreSnapshot) {
+	for final := range finalScores {
+		if err := g.settlement.SettleEvent(final, g.sportPrices(final.EventID)); err != nil {
+			log.Printf("Error settling event %d: %v", final.EventID, err)
+		}
+	}
 }
 
-func (g *Generator) startEvent(scoreSnapshot models.ScoreSnapshot, stopChan <-chan bool, wg *sync.WaitGroup) {
+func (g *Generator) startEvent(ctx context.Context, scoreSnapshot models.ScoreSnapshot, simulator eventSimulator, interval time.Duration, finalScores chan<- models.ScoreSnapshot, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	queueName := fmt.Sprintf("queue%v", scoreSnapshot.EventID)
@@ -42,42 +92,79 @@ func (g *Generator) startEvent(scoreSnapshot models.ScoreSnapshot, stopChan <-ch
 		fmt.Printf("failed to declare queue: %v\n", err)
 	}
 
+	control := g.addControl(scoreSnapshot.EventID)
+	defer g.removeControl(scoreSnapshot.EventID, control)
+
+	simulator.start(&scoreSnapshot)
+
 	if err = g.publishSnapshot(scoreSnapshot, queueName); err != nil {
 		log.Printf("Error publishing initial snapshot: %v", err)
 	}
 
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
+	run := &eventRun{ticker: time.NewTicker(interval), interval: interval}
+	defer run.ticker.Stop()
 
 	for {
 		select {
-		case <-stopChan:
-			fmt.Println("Stopping event simulation")
+		case <-ctx.Done():
+			log.Printf("Event %d stopped before it finished", scoreSnapshot.EventID)
 			return
-		case <-ticker.C:
-			rand.Seed(time.Now().UnixNano())
-			x := rand.Intn(2)
-
-			switch x {
-			case 0:
-				scoreSnapshot.Team1Score++
-				scoreSnapshot.Total++
-			case 1:
-				scoreSnapshot.Team2Score++
-				scoreSnapshot.Total++
+		case command := <-control.commands:
+			incident, err := g.applyCommand(run, &scoreSnapshot, command)
+			command.reply <- err
+
+			if incident != nil {
+				if err = g.publishIncident(*incident, queueName); err != nil {
+					log.Printf("Error publishing injected incident: %v", err)
+				}
+				if err = g.publishSnapshot(scoreSnapshot, queueName); err != nil {
+					log.Printf("Error publishing simulation score: %v", err)
+				}
+			}
+			if run.stopped {
+				log.Printf("Event %d stopped on request", scoreSnapshot.EventID)
+				return
 			}
+			continue
+		case <-run.ticks():
+		}
+
+		incidents, changed := simulator.tick(&scoreSnapshot)
+
+		for _, incident := range incidents {
+			incident.EventID = scoreSnapshot.EventID
 
+			if err = g.publishIncident(incident, queueName); err != nil {
+				log.Printf("Error publishing incident: %v", err)
+			}
+		}
+
+		if changed || simulator.snapshotDue() {
 			if err = g.publishSnapshot(scoreSnapshot, queueName); err != nil {
 				log.Printf("Error publishing simulation score: %v", err)
 			}
 		}
+
+		if simulator.finished() {
+			fmt.Println("Stopping event simulation")
+			finalScores <- scoreSnapshot
+			return
+		}
 	}
 }
 
 func (g *Generator) publishSnapshot(scoreSnapshot models.ScoreSnapshot, queueName string) error {
-	body, err := json.Marshal(scoreSnapshot)
+	return g.publishMessage(models.QueueMessage{Type: models.MessageTypeSnapshot, Snapshot: &scoreSnapshot}, queueName)
+}
+
+func (g *Generator) publishIncident(incident models.Incident, queueName string) error {
+	return g.publishMessage(models.QueueMessage{Type: models.MessageTypeIncident, Incident: &incident}, queueName)
+}
+
+func (g *Generator) publishMessage(queueMessage models.QueueMessage, queueName string) error {
+	body, err := json.Marshal(queueMessage)
 	if err != nil {
-		return fmt.Errorf("error marshalling snapshot: %w", err)
+		return fmt.Errorf("error marshalling %s message: %w", queueMessage.Type, err)
 	}
 
 	message := amqp.Publishing{
@@ -1,24 +1,49 @@
 package generator
 
 import (
+	"context"
 	"fmt"
 	"github.com/VaheMuradyan/Live2/cache"
 	"github.com/VaheMuradyan/Live2/centrifugoClient"
+	"github.com/VaheMuradyan/Live2/liability"
+	"github.com/VaheMuradyan/Live2/settlement"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"gorm.io/gorm"
+	"log"
 	"os"
+	"sync"
+	"time"
 )
 
 type Generator struct {
-	db       *gorm.DB
-	client   *centrifugoClient.CentrifugoClient
-	cache    *cache.Cache
-	channel  *amqp.Channel
-	conn     *amqp.Connection
-	stopChan chan bool
+	db         *gorm.DB
+	client     *centrifugoClient.CentrifugoClient
+	cache      *cache.Cache
+	settlement *settlement.SettlementService
+	liability  *liability.Tracker
+	channel    *amqp.Channel
+	conn       *amqp.Connection
+
+	suspensionWindows map[string]time.Duration
+
+	mu        sync.Mutex
+	state     string
+	startedAt time.Time
+	cancel    context.CancelFunc
+	done      chan struct{}
+
+	controlsMu sync.Mutex
+	controls   map[uint]*eventControl
+
+	openingMu sync.Mutex
+	opening   map[marketInstance]bool
+	openingWg sync.WaitGroup
+
+	reloadMu       sync.Mutex
+	reloadInterval time.Duration
 }
 
-func NewGenerator(client *centrifugoClient.CentrifugoClient, db *gorm.DB) *Generator {
+func NewGenerator(client *centrifugoClient.CentrifugoClient, db *gorm.DB, priceCache *cache.Cache, settlementService *settlement.SettlementService, tracker *liability.Tracker) *Generator {
 	rabbitmqURL := os.Getenv("RABBITMQ_URL")
 	if rabbitmqURL == "" {
 		rabbitmqURL = "amqp://localhost:5672"
@@ -35,17 +60,36 @@ func NewGenerator(client *centrifugoClient.CentrifugoClient, db *gorm.DB) *Gener
 	}
 
 	return &Generator{
-		db:       db,
-		client:   client,
-		cache:    cache.NewCache(db),
-		channel:  channel,
-		conn:     conn,
-		stopChan: make(chan bool),
+		db:         db,
+		client:     client,
+		cache:      priceCache,
+		settlement: settlementService,
+		liability:  tracker,
+		channel:    channel,
+		conn:       conn,
+
+		suspensionWindows: loadSuspensionWindows(),
+
+		state:    StateIdle,
+		controls: make(map[uint]*eventControl),
+		opening:  make(map[marketInstance]bool),
+
+		reloadInterval: loadReloadInterval(),
 	}
 }
 
-func (gen *Generator) Start() {
-	gen.cache.LoadStaticEventData()
-	go gen.startScoreMonitoring()
-	gen.startEventsSimulation()
+// loadReloadInterval reads how often STATIC_RELOAD_INTERVAL asks for the
+// static data to be reloaded during a simulation. Unset means never.
+func loadReloadInterval() time.Duration {
+	value := os.Getenv("STATIC_RELOAD_INTERVAL")
+	if value == "" {
+		return 0
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid STATIC_RELOAD_INTERVAL value %q, not reloading periodically", value)
+		return 0
+	}
+	return interval
 }
@@ -0,0 +1,114 @@
+package generator
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"github.com/VaheMuradyan/Live2/generator/markets"
+	"math"
+	"math/rand"
+)
+
+var hockeyPeriods = []string{models.PeriodHockey1, models.PeriodHockey2, models.PeriodHockey3}
+
+// hockeyGame plays three periods a minute at a time. A level game goes to
+// sudden-death overtime and then to a shootout, which counts as one goal
+// for its winner.
+type hockeyGame struct {
+	rng    *rand.Rand
+	model  markets.GoalModel
+	period int
+	minute int
+	end    int
+	done   bool
+}
+
+func newHockeyGame(rng *rand.Rand, model markets.GoalModel) *hockeyGame {
+	return &hockeyGame{
+		rng:   rng,
+		model: model,
+		end:   markets.HockeyPeriodMinutes,
+	}
+}
+
+func (h *hockeyGame) start(score *models.ScoreSnapshot) {
+	score.Sport = markets.SportHockey
+	score.Period = models.PeriodHockey1
+	score.Periods = []models.PeriodScore{{}}
+}
+
+func (h *hockeyGame) tick(score *models.ScoreSnapshot) ([]models.Incident, bool) {
+	if h.done {
+		return nil, false
+	}
+
+	h.minute++
+	score.Minute = h.minute
+
+	var incidents []models.Incident
+	for _, team := range []int{1, 2} {
+		rate := h.model.HomeRate
+		if team == 2 {
+			rate = h.model.AwayRate
+		}
+
+		if h.rng.Float64() < 1-math.Exp(-rate/markets.HockeyMinutes) {
+			incidents = append(incidents, h.goal(score, team))
+		}
+	}
+
+	if score.Period == models.PeriodOvertime && score.Team1Score != score.Team2Score {
+		return incidents, h.finish(score)
+	}
+	if h.minute < h.end {
+		return incidents, false
+	}
+
+	h.period++
+	switch {
+	case h.period < len(hockeyPeriods):
+		score.Period = hockeyPeriods[h.period]
+		h.end += markets.HockeyPeriodMinutes
+	case score.Team1Score != score.Team2Score:
+		return incidents, h.finish(score)
+	case score.Period != models.PeriodOvertime:
+		score.Period = models.PeriodOvertime
+		h.end += markets.OvertimeMinutes
+	default:
+		score.Period = models.PeriodShootout
+		score.Periods = append(score.Periods, models.PeriodScore{})
+
+		winner := 1
+		if h.rng.Float64() >= h.model.HomeShare() {
+			winner = 2
+		}
+		incidents = append(incidents, h.goal(score, winner))
+		return incidents, h.finish(score)
+	}
+
+	score.Periods = append(score.Periods, models.PeriodScore{})
+	return incidents, true
+}
+
+func (h *hockeyGame) goal(score *models.ScoreSnapshot, team int) models.Incident {
+	incident := models.Incident{
+		Type:   models.IncidentGoal,
+		Team:   team,
+		Minute: score.Minute,
+		Period: score.Period,
+	}
+	applyIncident(score, incident)
+	return incident
+}
+
+func (h *hockeyGame) finish(score *models.ScoreSnapshot) bool {
+	score.Period = models.PeriodFullTime
+	h.done = true
+	return true
+}
+
+func (h *hockeyGame) snapshotDue() bool {
+	return !h.done && h.minute%priceUpdateMinutes == 0
+}
+
+func (h *hockeyGame) finished() bool {
+	return h.done
+}
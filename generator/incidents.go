@@ -0,0 +1,169 @@
+package generator
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"github.com/VaheMuradyan/Live2/generator/markets"
+	"math"
+	"math/rand"
+)
+
+const (
+	ownGoalShare          = 0.05
+	varReviewChance       = 0.12
+	varConfirmChance      = 0.7
+	penaltyRate           = 0.25
+	penaltyScoredChance   = 0.78
+	maxSubstitutions      = 5
+	substitutionPerMinute = 0.12
+)
+
+// footballSimulator draws the incidents of a football match minute by
+// minute. A goal under VAR review or an awarded penalty is resolved in the
+// following minute.
+type footballSimulator struct {
+	rng            *rand.Rand
+	model          markets.GoalModel
+	pendingReview  int
+	pendingPenalty int
+	substitutions  [2]int
+}
+
+func newFootballSimulator(rng *rand.Rand, model markets.GoalModel) *footballSimulator {
+	return &footballSimulator{
+		rng:   rng,
+		model: model,
+	}
+}
+
+func (s *footballSimulator) nextMinute(score models.ScoreSnapshot) []models.Incident {
+	if s.pendingReview != 0 {
+		team := s.pendingReview
+		s.pendingReview = 0
+		if s.rng.Float64() < varConfirmChance {
+			return []models.Incident{{Type: models.IncidentGoal, Team: team}}
+		}
+		return []models.Incident{{Type: models.IncidentGoalCancelled, Team: team}}
+	}
+
+	if s.pendingPenalty != 0 {
+		team := s.pendingPenalty
+		s.pendingPenalty = 0
+		if s.rng.Float64() < penaltyScoredChance {
+			return []models.Incident{{Type: models.IncidentPenaltyScored, Team: team}}
+		}
+		return []models.Incident{{Type: models.IncidentPenaltyMissed, Team: team}}
+	}
+
+	var incidents []models.Incident
+	model := s.model.WithRedCards(score.Team1RedCards, score.Team2RedCards)
+
+	for _, team := range []int{1, 2} {
+		rate, share := model.HomeRate, model.HomeShare()
+		if team == 2 {
+			rate, share = model.AwayRate, 1-share
+		}
+
+		if s.happens(rate) {
+			incidents = append(incidents, s.goal(team))
+		} else if s.pendingPenalty == 0 && s.happens(penaltyRate) {
+			s.pendingPenalty = team
+			incidents = append(incidents, models.Incident{Type: models.IncidentPenaltyAwarded, Team: team})
+		}
+
+		if s.happens(markets.CornersPerMatch * share) {
+			incidents = append(incidents, models.Incident{Type: models.IncidentCorner, Team: team})
+		}
+		if s.happens(markets.CardsPerMatch/2 - markets.RedCardsPerTeam) {
+			incidents = append(incidents, models.Incident{Type: models.IncidentYellowCard, Team: team})
+		}
+		if s.happens(markets.RedCardsPerTeam) {
+			incidents = append(incidents, models.Incident{Type: models.IncidentRedCard, Team: team})
+		}
+
+		if score.Period == models.PeriodSecondHalf && s.substitutions[team-1] < maxSubstitutions &&
+			s.rng.Float64() < substitutionPerMinute {
+			s.substitutions[team-1]++
+			incidents = append(incidents, models.Incident{Type: models.IncidentSubstitution, Team: team})
+		}
+	}
+
+	return incidents
+}
+
+func (s *footballSimulator) goal(team int) models.Incident {
+	if s.rng.Float64() < ownGoalShare {
+		return models.Incident{Type: models.IncidentOwnGoal, Team: opponent(team)}
+	}
+	if s.pendingPenalty == 0 && s.rng.Float64() < varReviewChance {
+		s.pendingReview = team
+		return models.Incident{Type: models.IncidentVARReview, Team: team}
+	}
+	return models.Incident{Type: models.IncidentGoal, Team: team}
+}
+
+// happens draws whether an incident with the given full-match rate occurs
+// in a single minute.
+func (s *footballSimulator) happens(rate float64) bool {
+	return s.rng.Float64() < 1-math.Exp(-rate/markets.MatchMinutes)
+}
+
+func opponent(team int) int {
+	if team == 1 {
+		return 2
+	}
+	return 1
+}
+
+// applyIncident updates the score with the effect of an incident and
+// reports whether the score itself changed.
+func applyIncident(score *models.ScoreSnapshot, incident models.Incident) bool {
+	score.Minute = incident.Minute
+	score.AddedTime = incident.AddedTime
+	score.Period = incident.Period
+
+	switch incident.Type {
+	case models.IncidentGoal, models.IncidentPenaltyScored:
+		addGoal(score, incident.Team)
+		return true
+	case models.IncidentOwnGoal:
+		addGoal(score, opponent(incident.Team))
+		return true
+	case models.IncidentRedCard:
+		if incident.Team == 1 {
+			score.Team1RedCards++
+		} else {
+			score.Team2RedCards++
+		}
+	case models.IncidentYellowCard:
+		if incident.Team == 1 {
+			score.Team1YellowCards++
+		} else {
+			score.Team2YellowCards++
+		}
+	case models.IncidentCorner:
+		if incident.Team == 1 {
+			score.Team1Corners++
+		} else {
+			score.Team2Corners++
+		}
+	}
+	return false
+}
+
+func addGoal(score *models.ScoreSnapshot, team int) {
+	if team == 1 {
+		score.Team1Score++
+	} else {
+		score.Team2Score++
+	}
+	score.Total++
+	if len(score.Periods) > 0 {
+		period := &score.Periods[len(score.Periods)-1]
+		if team == 1 {
+			period.Team1++
+		} else {
+			period.Team2++
+		}
+	}
+	score.Goals = append(score.Goals, models.Goal{Team: team, Minute: score.Minute})
+}
@@ -0,0 +1,229 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"github.com/VaheMuradyan/Live2/cache"
+	"github.com/VaheMuradyan/Live2/db/models"
+	"log"
+	"time"
+)
+
+const (
+	StateIdle     = "idle"
+	StateStarting = "starting"
+	StateRunning  = "running"
+	StateStopping = "stopping"
+)
+
+var (
+	ErrAlreadyRunning = errors.New("a simulation is already running")
+	ErrNotRunning     = errors.New("no simulation is running")
+)
+
+type Status struct {
+	State            string     `json:"state"`
+	StartedAt        *time.Time `json:"started_at,omitempty"`
+	PriceCacheMisses int64      `json:"price_cache_misses"`
+}
+
+// Start launches a simulation in the background and rejects it while
+// another one is starting or running. prepare, when given, runs once the
+// generator is reserved for this simulation and before it starts, so
+// activating its events cannot race another start. The simulation ends
+// when its events finish, when ctx is cancelled or when Stop is called.
+func (g *Generator) Start(ctx context.Context, data models.RequestData, prepare func() error) error {
+	g.mu.Lock()
+	if g.state != StateIdle {
+		g.mu.Unlock()
+		return ErrAlreadyRunning
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	g.state = StateStarting
+	g.startedAt = time.Now()
+	g.cancel = cancel
+	g.done = done
+	g.mu.Unlock()
+
+	finish := func() {
+		g.mu.Lock()
+		g.state = StateIdle
+		g.cancel = nil
+		g.mu.Unlock()
+
+		cancel()
+		close(done)
+	}
+
+	if prepare != nil {
+		if err := prepare(); err != nil {
+			finish()
+			return err
+		}
+	}
+
+	g.mu.Lock()
+	if err := runCtx.Err(); err != nil {
+		g.mu.Unlock()
+		finish()
+		return err
+	}
+	g.state = StateRunning
+	g.mu.Unlock()
+
+	go func() {
+		g.run(runCtx, data)
+		finish()
+	}()
+
+	return nil
+}
+
+// Stop cancels the running simulation and waits until its consumers are
+// drained and its prices saved, or until ctx is done.
+func (g *Generator) Stop(ctx context.Context) error {
+	g.mu.Lock()
+	if g.state == StateIdle {
+		g.mu.Unlock()
+		return nil
+	}
+	g.state = StateStopping
+	g.cancel()
+	done := g.done
+	g.mu.Unlock()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (g *Generator) Status() Status {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	status := Status{State: g.state, PriceCacheMisses: g.cache.PriceMisses()}
+	if g.state != StateIdle {
+		startedAt := g.startedAt
+		status.StartedAt = &startedAt
+	}
+	return status
+}
+
+// Close releases the RabbitMQ channel and connection.
+func (g *Generator) Close() {
+	if g.channel != nil {
+		if err := g.channel.Close(); err != nil {
+			log.Printf("Error closing RabbitMQ channel: %v", err)
+		}
+	}
+	if g.conn != nil {
+		if err := g.conn.Close(); err != nil {
+			log.Printf("Error closing RabbitMQ connection: %v", err)
+		}
+	}
+}
+
+// ReloadStaticData picks up changes to the running events, their teams and
+// markets made in MySQL since the simulation started.
+func (g *Generator) ReloadStaticData() (cache.ReloadResult, error) {
+	g.reloadMu.Lock()
+	defer g.reloadMu.Unlock()
+
+	if g.Status().State != StateRunning {
+		return cache.ReloadResult{}, ErrNotRunning
+	}
+
+	result, err := g.cache.ReloadStaticEventData()
+	if err != nil {
+		return result, err
+	}
+
+	for _, eventCode := range result.RemovedEvents {
+		g.retireEvent(eventCode)
+	}
+
+	log.Printf("Reloaded static data: %+v", result)
+	return result, nil
+}
+
+// retireEvent stops an event deactivated in MySQL and saves its prices. An
+// event that already finished is left for the end of the run to settle.
+func (g *Generator) retireEvent(eventCode string) {
+	event, exists := g.cache.GetEventByCode(eventCode)
+	if !exists {
+		return
+	}
+
+	if err := g.StopEvent(eventCode); err != nil {
+		log.Printf("Event %s was deactivated but is not running: %v", eventCode, err)
+		return
+	}
+	g.cache.RetireEvent(event.ID)
+	g.closeBets(event.ID)
+}
+
+// closeBets closes what is still open on an ended event so its stakes stop
+// counting towards the liability of later runs.
+func (g *Generator) closeBets(eventID uint) {
+	if err := g.settlement.CloseEventBets(eventID); err != nil {
+		log.Printf("Error closing bets of event %d: %v", eventID, err)
+	}
+	g.liability.ClearEvent(eventID)
+}
+
+// reloadPeriodically reloads the static data every reloadInterval until
+// ctx is done. It does nothing when no interval is configured.
+func (g *Generator) reloadPeriodically(ctx context.Context) {
+	if g.reloadInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(g.reloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := g.ReloadStaticData(); err != nil {
+				log.Printf("Error reloading static data: %v", err)
+			}
+		}
+	}
+}
+
+func (g *Generator) run(ctx context.Context, data models.RequestData) {
+	g.cache.LoadStaticEventData()
+	consumers, monitoring := g.startScoreMonitoring()
+
+	go g.reloadPeriodically(ctx)
+
+	finalScores := g.startEventsSimulation(ctx, data)
+
+	g.stopScoreMonitoring(consumers, monitoring)
+	g.openingWg.Wait()
+
+	// A reload from here on would change the events being saved and settled.
+	g.reloadMu.Lock()
+	defer g.reloadMu.Unlock()
+
+	g.mu.Lock()
+	g.state = StateStopping
+	g.mu.Unlock()
+
+	g.cache.SaveData()
+	g.settleEvents(finalScores)
+
+	for _, event := range g.cache.GetActiveEvents() {
+		g.cache.EndEvent(event.ID)
+		g.closeBets(event.ID)
+	}
+	log.Println("Simulation finished")
+}
@@ -0,0 +1,81 @@
+package generator
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"github.com/VaheMuradyan/Live2/generator/markets"
+	"log"
+)
+
+// marketInstance names a market instance of an event being opened.
+type marketInstance struct {
+	eventID    uint
+	marketCode string
+}
+
+// openMarketInstances opens the current instance of every market family
+// the event offers, such as Next Goal #3 after the second goal, unless it
+// is open or being opened already.
+func (g *Generator) openMarketInstances(score models.ScoreSnapshot) {
+	marketPrices := g.cache.GetEventMarketPrices(score.EventID)
+
+	for _, family := range markets.Families() {
+		if !offersFamily(marketPrices, family) {
+			continue
+		}
+
+		definition, ok := family.NextInstance(score)
+		if !ok {
+			continue
+		}
+		if _, exists := marketPrices[definition.Code]; exists {
+			continue
+		}
+
+		g.openMarketInstance(score, definition)
+	}
+}
+
+// openMarketInstance creates the instance in the background, since it is
+// written to MySQL, so pricing the event does not wait on it. Its prices
+// are priced at the event's latest score and announced once it is open.
+func (g *Generator) openMarketInstance(score models.ScoreSnapshot, definition markets.MarketDefinition) {
+	instance := marketInstance{eventID: score.EventID, marketCode: definition.Code}
+
+	g.openingMu.Lock()
+	defer g.openingMu.Unlock()
+
+	if g.opening[instance] {
+		return
+	}
+	g.opening[instance] = true
+
+	g.openingWg.Add(1)
+	go func() {
+		defer g.openingWg.Done()
+		defer func() {
+			g.openingMu.Lock()
+			delete(g.opening, instance)
+			g.openingMu.Unlock()
+		}()
+
+		if err := g.cache.AddMarketInstance(score.EventID, definition); err != nil {
+			log.Printf("Error opening market %s for event %d: %v", definition.Code, score.EventID, err)
+			return
+		}
+		log.Printf("Opened market %s for event %d", definition.Code, score.EventID)
+
+		if latest, exists := g.cache.GetEventScore(score.EventID); exists {
+			score = latest
+		}
+		g.sendActiveCoefficients(score.EventID, score, definition.Code)
+	}()
+}
+
+func offersFamily(marketPrices map[string]map[string]uint, family markets.MarketFamily) bool {
+	for marketCode := range marketPrices {
+		if family.Owns(marketCode) {
+			return true
+		}
+	}
+	return false
+}
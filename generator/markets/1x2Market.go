@@ -2,33 +2,42 @@ package markets
 
 import (
 	"github.com/VaheMuradyan/Live2/db/models"
-
-	"math/rand"
 )
 
-func Calculate1x2Coefficient(priceCode string, score models.ScoreSnapshot) float64 {
-	scoreDiff := score.Team1Score - score.Team2Score
+type oneXTwoMarket struct{}
+
+func init() {
+	Register(oneXTwoMarket{})
+}
+
+func (oneXTwoMarket) MarketCodes() []string {
+	return []string{"1X2"}
+}
+
+func (oneXTwoMarket) Price(marketCode, priceCode string, score models.ScoreSnapshot, model GoalModel) (float64, error) {
+	switch priceCode {
+	case "1":
+		return FairCoefficient(model.Probability(score, func(home, away int) bool { return home > away })), nil
+	case "X":
+		return FairCoefficient(model.Probability(score, func(home, away int) bool { return home == away })), nil
+	case "2":
+		return FairCoefficient(model.Probability(score, func(home, away int) bool { return home < away })), nil
+	}
+	return 0, unknownPrice(marketCode, priceCode)
+}
+
+func (oneXTwoMarket) ClosedPrices(marketCode string, score models.ScoreSnapshot) []string {
+	return nil
+}
 
+func (oneXTwoMarket) Settle(marketCode, priceCode string, final models.ScoreSnapshot) (Outcome, error) {
 	switch priceCode {
 	case "1":
-		if scoreDiff > 0 {
-			return 1.1 + float64(rand.Intn(50))/100
-		} else if scoreDiff < 0 {
-			return 3.0 + float64(rand.Intn(200))/100
-		}
-		return 2.0 + float64(rand.Intn(100))/100
+		return outcomeOf(final.Team1Score > final.Team2Score), nil
 	case "X":
-		if scoreDiff == 0 {
-			return 2.5 + float64(rand.Intn(100))/100
-		}
-		return 3.0 + float64(rand.Intn(150))/100
+		return outcomeOf(final.Team1Score == final.Team2Score), nil
 	case "2":
-		if scoreDiff < 0 {
-			return 1.1 + float64(rand.Intn(50))/100
-		} else if scoreDiff > 0 {
-			return 3.0 + float64(rand.Intn(200))/100
-		}
-		return 2.0 + float64(rand.Intn(100))/100
+		return outcomeOf(final.Team1Score < final.Team2Score), nil
 	}
-	return 2.0
+	return OutcomeVoid, unknownPrice(marketCode, priceCode)
 }
@@ -0,0 +1,162 @@
+package markets
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"math"
+	"strconv"
+)
+
+const (
+	BasketballMinutes = 48
+	QuarterMinutes    = BasketballMinutes / 4
+	OvertimeMinutes   = 5
+
+	basketballPointRate   = 112.0
+	basketballAdvantage   = 1.015
+	basketballRatingScale = 0.004
+	// pointVariance is the variance of the points added per point expected,
+	// widened for the runs both sides go on.
+	pointVariance = 3.0
+)
+
+func NewBasketballModel(homeRating, awayRating int) GoalModel {
+	return newRatedModel(homeRating, awayRating, basketballPointRate, basketballAdvantage, basketballRatingScale, BasketballMinutes)
+}
+
+// basketballMarket prices the moneyline (BML) and point spreads such as
+// BSPM55, home -5.5, or BSPP25, home +2.5. Prices add "_1" for the home
+// side and "_2" for the away side.
+type basketballMarket struct {
+	codes []string
+	lines map[string]float64
+}
+
+func init() {
+	market := basketballMarket{codes: []string{"BML"}, lines: make(map[string]float64)}
+	for _, line := range []float64{-9.5, -5.5, -2.5, 2.5, 5.5, 9.5} {
+		code := "BSP" + handicapSuffix(line)
+		market.codes = append(market.codes, code)
+		market.lines[code] = line
+	}
+	Register(market)
+}
+
+func (basketballMarket) Sport() string {
+	return SportBasketball
+}
+
+func (m basketballMarket) MarketCodes() []string {
+	return m.codes
+}
+
+func (m basketballMarket) Definitions() []MarketDefinition {
+	definitions := []MarketDefinition{{
+		Code:           "BML",
+		Name:           "Basketball Moneyline",
+		CollectionCode: "BASKETBALL",
+		CollectionName: "Basketball",
+		Prices: []PriceDefinition{
+			{Code: "BML_1", Name: "Basketball Moneyline Team 1"},
+			{Code: "BML_2", Name: "Basketball Moneyline Team 2"},
+		},
+	}}
+
+	for _, code := range m.codes[1:] {
+		line := strconv.FormatFloat(m.lines[code], 'f', 1, 64)
+		if m.lines[code] > 0 {
+			line = "+" + line
+		}
+		name := "Basketball Point Spread " + line
+
+		definitions = append(definitions, MarketDefinition{
+			Code:           code,
+			Name:           name,
+			CollectionCode: "BASKETBALL",
+			CollectionName: "Basketball",
+			Prices: []PriceDefinition{
+				{Code: code + "_1", Name: name + " Team 1"},
+				{Code: code + "_2", Name: name + " Team 2"},
+			},
+		})
+	}
+	return definitions
+}
+
+func (m basketballMarket) Price(marketCode, priceCode string, score models.ScoreSnapshot, model GoalModel) (float64, error) {
+	line, ok := m.line(marketCode)
+	if !ok {
+		return 0, unknownPrice(marketCode, priceCode)
+	}
+
+	home := marginAbove(score, model, -line)
+
+	switch priceCode {
+	case marketCode + "_1":
+		return FairCoefficient(home), nil
+	case marketCode + "_2":
+		return FairCoefficient(1 - home), nil
+	}
+	return 0, unknownPrice(marketCode, priceCode)
+}
+
+func (basketballMarket) ClosedPrices(marketCode string, score models.ScoreSnapshot) []string {
+	return nil
+}
+
+func (m basketballMarket) Settle(marketCode, priceCode string, final models.ScoreSnapshot) (Outcome, error) {
+	line, ok := m.line(marketCode)
+	if !ok {
+		return OutcomeVoid, unknownPrice(marketCode, priceCode)
+	}
+
+	covered := float64(final.Team1Score-final.Team2Score)+line > 0
+
+	switch priceCode {
+	case marketCode + "_1":
+		return outcomeOf(covered), nil
+	case marketCode + "_2":
+		return outcomeOf(!covered), nil
+	}
+	return OutcomeVoid, unknownPrice(marketCode, priceCode)
+}
+
+func (m basketballMarket) line(marketCode string) (float64, bool) {
+	if marketCode == "BML" {
+		return 0, true
+	}
+	line, ok := m.lines[marketCode]
+	return line, ok
+}
+
+// marginAbove approximates the chance that the home side finishes more than
+// threshold points ahead with a normal distribution of the points still to
+// come. Ties in regulation go to overtime, which is close to a coin flip,
+// so a threshold of zero splits them evenly.
+func marginAbove(score models.ScoreSnapshot, model GoalModel, threshold float64) float64 {
+	left := float64(max(model.length()-score.Minute, 0)) / float64(model.length())
+	if score.Period == models.PeriodOvertime {
+		left = float64(max(model.length()+OvertimeMinutes*overtimesPlayed(score)-score.Minute, 0)) / float64(model.length())
+	}
+
+	mean := float64(score.Team1Score-score.Team2Score) + (model.HomeRate-model.AwayRate)*left
+	deviation := math.Sqrt((model.HomeRate + model.AwayRate) * left * pointVariance)
+
+	if deviation == 0 {
+		switch {
+		case mean > threshold:
+			return 1
+		case mean == threshold && threshold == 0:
+			return model.HomeShare()
+		}
+		return 0
+	}
+	return 1 - normalCDF((threshold-mean)/deviation)
+}
+
+func overtimesPlayed(score models.ScoreSnapshot) int {
+	return max(len(score.Periods)-4, 1)
+}
+
+func normalCDF(x float64) float64 {
+	return 0.5 * (1 + math.Erf(x/math.Sqrt2))
+}
@@ -2,23 +2,43 @@ package markets
 
 import (
 	"github.com/VaheMuradyan/Live2/db/models"
-	"math/rand"
 )
 
-func CalculateBTTSCoefficient(priceCode string, score models.ScoreSnapshot) float64 {
-	bothTeamsScored := score.Team1Score > 0 && score.Team2Score > 0
+type bttsMarket struct{}
+
+func init() {
+	Register(bttsMarket{})
+}
+
+func (bttsMarket) MarketCodes() []string {
+	return []string{"BTTS"}
+}
+
+func (bttsMarket) Price(marketCode, priceCode string, score models.ScoreSnapshot, model GoalModel) (float64, error) {
+	switch priceCode {
+	case "BTTS_Y":
+		return FairCoefficient(model.Probability(score, func(home, away int) bool { return home > 0 && away > 0 })), nil
+	case "BTTS_N":
+		return FairCoefficient(model.Probability(score, func(home, away int) bool { return home == 0 || away == 0 })), nil
+	}
+	return 0, unknownPrice(marketCode, priceCode)
+}
+
+func (bttsMarket) ClosedPrices(marketCode string, score models.ScoreSnapshot) []string {
+	if score.Team1Score > 0 && score.Team2Score > 0 {
+		return []string{"BTTS_Y", "BTTS_N"}
+	}
+	return nil
+}
+
+func (bttsMarket) Settle(marketCode, priceCode string, final models.ScoreSnapshot) (Outcome, error) {
+	bothTeamsScored := final.Team1Score > 0 && final.Team2Score > 0
 
 	switch priceCode {
 	case "BTTS_Y":
-		if bothTeamsScored {
-			return 1.2 + float64(rand.Intn(30))/100
-		}
-		return 1.8 + float64(rand.Intn(60))/100
+		return outcomeOf(bothTeamsScored), nil
 	case "BTTS_N":
-		if bothTeamsScored {
-			return 4.0 + float64(rand.Intn(200))/100
-		}
-		return 1.5 + float64(rand.Intn(40))/100
+		return outcomeOf(!bothTeamsScored), nil
 	}
-	return 1.9
+	return OutcomeVoid, unknownPrice(marketCode, priceCode)
 }
@@ -0,0 +1,92 @@
+package markets
+
+import (
+	"fmt"
+	"github.com/VaheMuradyan/Live2/db/models"
+)
+
+const (
+	correctScoreMax   = 5
+	correctScoreOther = "CS_OTHER"
+)
+
+type correctScoreMarket struct{}
+
+func init() {
+	Register(correctScoreMarket{})
+}
+
+func (correctScoreMarket) MarketCodes() []string {
+	return []string{"CS"}
+}
+
+func (correctScoreMarket) Definitions() []MarketDefinition {
+	definition := MarketDefinition{
+		Code:           "CS",
+		Name:           "Correct Score",
+		CollectionCode: "SCORE",
+		CollectionName: "Score",
+	}
+
+	for home := 0; home <= correctScoreMax; home++ {
+		for away := 0; away <= correctScoreMax; away++ {
+			definition.Prices = append(definition.Prices, PriceDefinition{
+				Code: correctScoreCode(home, away),
+				Name: fmt.Sprintf("Correct Score %d-%d", home, away),
+			})
+		}
+	}
+	definition.Prices = append(definition.Prices, PriceDefinition{Code: correctScoreOther, Name: "Correct Score Any Other"})
+
+	return []MarketDefinition{definition}
+}
+
+func (correctScoreMarket) Price(marketCode, priceCode string, score models.ScoreSnapshot, model GoalModel) (float64, error) {
+	if priceCode == correctScoreOther {
+		return FairCoefficient(model.Probability(score, func(home, away int) bool {
+			return home > correctScoreMax || away > correctScoreMax
+		})), nil
+	}
+
+	var target [2]int
+	if _, err := fmt.Sscanf(priceCode, "CS_%d_%d", &target[0], &target[1]); err != nil {
+		return 0, unknownPrice(marketCode, priceCode)
+	}
+
+	return FairCoefficient(model.Probability(score, func(home, away int) bool {
+		return home == target[0] && away == target[1]
+	})), nil
+}
+
+func (correctScoreMarket) ClosedPrices(marketCode string, score models.ScoreSnapshot) []string {
+	var closed []string
+
+	for home := 0; home <= correctScoreMax; home++ {
+		for away := 0; away <= correctScoreMax; away++ {
+			if score.Team1Score > home || score.Team2Score > away {
+				closed = append(closed, correctScoreCode(home, away))
+			}
+		}
+	}
+
+	return closed
+}
+
+func (correctScoreMarket) Settle(marketCode, priceCode string, final models.ScoreSnapshot) (Outcome, error) {
+	outsideGrid := final.Team1Score > correctScoreMax || final.Team2Score > correctScoreMax
+
+	if priceCode == correctScoreOther {
+		return outcomeOf(outsideGrid), nil
+	}
+
+	var target [2]int
+	if _, err := fmt.Sscanf(priceCode, "CS_%d_%d", &target[0], &target[1]); err != nil {
+		return OutcomeVoid, unknownPrice(marketCode, priceCode)
+	}
+
+	return outcomeOf(final.Team1Score == target[0] && final.Team2Score == target[1]), nil
+}
+
+func correctScoreCode(home, away int) string {
+	return fmt.Sprintf("CS_%d_%d", home, away)
+}
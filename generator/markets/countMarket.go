@@ -0,0 +1,212 @@
+package markets
+
+import (
+	"fmt"
+	"github.com/VaheMuradyan/Live2/db/models"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	CornersPerMatch = 10.0
+	CardsPerMatch   = 3.8
+	RedCardsPerTeam = 0.1
+)
+
+// countOverUnderMarket prices over/under lines on a match counter other than
+// goals, such as corners or cards. Market codes look like "OUC95" and price
+// codes like "OC95"/"UC95" for the 9.5 corners line.
+type countOverUnderMarket struct {
+	prefix     string
+	name       string
+	collection MarketDefinition
+	codes      []string
+	lines      map[string]float64
+	count      func(score models.ScoreSnapshot) int
+	perMatch   float64
+}
+
+func init() {
+	Register(newCountOverUnderMarket("C", "Corners", "CORNERS", []float64{7.5, 8.5, 9.5, 10.5, 11.5}, CornersPerMatch,
+		func(score models.ScoreSnapshot) int { return score.Team1Corners + score.Team2Corners }))
+
+	Register(newCountOverUnderMarket("K", "Cards", "CARDS", []float64{2.5, 3.5, 4.5, 5.5}, CardsPerMatch,
+		func(score models.ScoreSnapshot) int {
+			return score.Team1YellowCards + score.Team2YellowCards + score.Team1RedCards + score.Team2RedCards
+		}))
+
+	Register(redCardMarket{})
+}
+
+func newCountOverUnderMarket(prefix, name, collectionCode string, lines []float64, perMatch float64,
+	count func(score models.ScoreSnapshot) int) countOverUnderMarket {
+	market := countOverUnderMarket{
+		prefix:     prefix,
+		name:       name,
+		collection: MarketDefinition{CollectionCode: collectionCode, CollectionName: name},
+		lines:      make(map[string]float64),
+		count:      count,
+		perMatch:   perMatch,
+	}
+
+	for _, line := range lines {
+		suffix := prefix + strings.ReplaceAll(strconv.FormatFloat(line, 'f', -1, 64), ".", "")
+		market.codes = append(market.codes, "OU"+suffix)
+		market.lines[suffix] = line
+	}
+
+	return market
+}
+
+func (m countOverUnderMarket) MarketCodes() []string {
+	return m.codes
+}
+
+func (m countOverUnderMarket) Definitions() []MarketDefinition {
+	var definitions []MarketDefinition
+
+	for _, code := range m.codes {
+		suffix := code[len("OU"):]
+		line := strconv.FormatFloat(m.lines[suffix], 'f', -1, 64)
+
+		definitions = append(definitions, MarketDefinition{
+			Code:           code,
+			Name:           fmt.Sprintf("Total %s %s", m.name, line),
+			CollectionCode: m.collection.CollectionCode,
+			CollectionName: m.collection.CollectionName,
+			Prices: []PriceDefinition{
+				{Code: "O" + suffix, Name: fmt.Sprintf("Over %s %s", m.name, line)},
+				{Code: "U" + suffix, Name: fmt.Sprintf("Under %s %s", m.name, line)},
+			},
+		})
+	}
+
+	return definitions
+}
+
+func (m countOverUnderMarket) Price(marketCode, priceCode string, score models.ScoreSnapshot, model GoalModel) (float64, error) {
+	suffix := marketCode[len("OU"):]
+
+	line, ok := m.lines[suffix]
+	if !ok {
+		return 0, unknownPrice(marketCode, priceCode)
+	}
+
+	needed := int(math.Floor(line)) - m.count(score) + 1
+	over := poissonAtLeast(m.perMatch*remainingShare(score.Minute), needed)
+
+	switch priceCode {
+	case "O" + suffix:
+		return FairCoefficient(over), nil
+	case "U" + suffix:
+		return FairCoefficient(1 - over), nil
+	}
+	return 0, unknownPrice(marketCode, priceCode)
+}
+
+func (m countOverUnderMarket) ClosedPrices(marketCode string, score models.ScoreSnapshot) []string {
+	suffix := marketCode[len("OU"):]
+
+	if line, ok := m.lines[suffix]; ok && float64(m.count(score)) > line {
+		return []string{"O" + suffix, "U" + suffix}
+	}
+	return nil
+}
+
+func (m countOverUnderMarket) Settle(marketCode, priceCode string, final models.ScoreSnapshot) (Outcome, error) {
+	suffix := marketCode[len("OU"):]
+
+	line, ok := m.lines[suffix]
+	if !ok {
+		return OutcomeVoid, unknownPrice(marketCode, priceCode)
+	}
+
+	over := float64(m.count(final)) > line
+
+	switch priceCode {
+	case "O" + suffix:
+		return outcomeOf(over), nil
+	case "U" + suffix:
+		return outcomeOf(!over), nil
+	}
+	return OutcomeVoid, unknownPrice(marketCode, priceCode)
+}
+
+// redCardMarket offers "team to receive a red card" for each side under the
+// market codes RC1 and RC2.
+type redCardMarket struct{}
+
+func (redCardMarket) MarketCodes() []string {
+	return []string{"RC1", "RC2"}
+}
+
+func (redCardMarket) Definitions() []MarketDefinition {
+	var definitions []MarketDefinition
+
+	for team, code := range []string{"RC1", "RC2"} {
+		name := fmt.Sprintf("Team %d To Receive A Red Card", team+1)
+		definitions = append(definitions, MarketDefinition{
+			Code:           code,
+			Name:           name,
+			CollectionCode: "CARDS",
+			CollectionName: "Cards",
+			Prices: []PriceDefinition{
+				{Code: code + "_Y", Name: name + " Yes"},
+				{Code: code + "_N", Name: name + " No"},
+			},
+		})
+	}
+
+	return definitions
+}
+
+func (m redCardMarket) Price(marketCode, priceCode string, score models.ScoreSnapshot, model GoalModel) (float64, error) {
+	cards, err := m.redCards(marketCode, priceCode, score)
+	if err != nil {
+		return 0, err
+	}
+
+	yes := 1.0
+	if cards == 0 {
+		yes = poissonAtLeast(RedCardsPerTeam*remainingShare(score.Minute), 1)
+	}
+
+	if priceCode == marketCode+"_Y" {
+		return FairCoefficient(yes), nil
+	}
+	return FairCoefficient(1 - yes), nil
+}
+
+func (m redCardMarket) ClosedPrices(marketCode string, score models.ScoreSnapshot) []string {
+	if cards, err := m.redCards(marketCode, marketCode+"_Y", score); err == nil && cards > 0 {
+		return []string{marketCode + "_Y", marketCode + "_N"}
+	}
+	return nil
+}
+
+func (m redCardMarket) Settle(marketCode, priceCode string, final models.ScoreSnapshot) (Outcome, error) {
+	cards, err := m.redCards(marketCode, priceCode, final)
+	if err != nil {
+		return OutcomeVoid, err
+	}
+
+	if priceCode == marketCode+"_Y" {
+		return outcomeOf(cards > 0), nil
+	}
+	return outcomeOf(cards == 0), nil
+}
+
+func (redCardMarket) redCards(marketCode, priceCode string, score models.ScoreSnapshot) (int, error) {
+	if priceCode != marketCode+"_Y" && priceCode != marketCode+"_N" {
+		return 0, unknownPrice(marketCode, priceCode)
+	}
+
+	switch marketCode {
+	case "RC1":
+		return score.Team1RedCards, nil
+	case "RC2":
+		return score.Team2RedCards, nil
+	}
+	return 0, unknownPrice(marketCode, priceCode)
+}
@@ -0,0 +1,181 @@
+package markets
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"math"
+)
+
+const (
+	MatchMinutes = 90
+	HalfMinutes  = MatchMinutes / 2
+
+	baseGoalRate    = 1.35
+	homeAdvantage   = 1.1
+	ratingScale     = 0.02
+	redCardOwnRate  = 0.7
+	redCardOppRate  = 1.2
+	maxGoals        = 10
+	MinCoefficient  = 1.01
+	MaxCoefficient  = 100.0
+	coefficientStep = 100
+)
+
+// GoalModel holds the full-match goal (or point) expectations of both teams
+// over a match of Minutes minutes, derived from their ratings. Tennis has no
+// clock and uses the chance of each player winning a point on serve instead.
+type GoalModel struct {
+	HomeRate  float64
+	AwayRate  float64
+	Minutes   int
+	HomeServe float64
+	AwayServe float64
+}
+
+func NewGoalModel(homeRating, awayRating int) GoalModel {
+	return newRatedModel(homeRating, awayRating, baseGoalRate, homeAdvantage, ratingScale, MatchMinutes)
+}
+
+func newRatedModel(homeRating, awayRating int, base, advantage, scale float64, minutes int) GoalModel {
+	diff := float64(homeRating - awayRating)
+
+	return GoalModel{
+		HomeRate: base * advantage * math.Exp(scale*diff),
+		AwayRate: base * math.Exp(-scale*diff),
+		Minutes:  minutes,
+	}
+}
+
+func (m GoalModel) length() int {
+	if m.Minutes > 0 {
+		return m.Minutes
+	}
+	return MatchMinutes
+}
+
+// WithRedCards adjusts the goal rates for the players each side has lost.
+func (m GoalModel) WithRedCards(homeCards, awayCards int) GoalModel {
+	for i := 0; i < homeCards; i++ {
+		m.HomeRate *= redCardOwnRate
+		m.AwayRate *= redCardOppRate
+	}
+	for i := 0; i < awayCards; i++ {
+		m.AwayRate *= redCardOwnRate
+		m.HomeRate *= redCardOppRate
+	}
+	return m
+}
+
+// HomeShare is the part of the attacking play, and so of corners, that
+// belongs to the home side.
+func (m GoalModel) HomeShare() float64 {
+	if m.HomeRate+m.AwayRate == 0 {
+		return 0.5
+	}
+	return m.HomeRate / (m.HomeRate + m.AwayRate)
+}
+
+func remainingShare(minute int) float64 {
+	left := float64(MatchMinutes-minute) / MatchMinutes
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+// Distribution returns the probability of every final score reachable from
+// the current one. Entry [i][j] is the chance that the home side scores i
+// more goals and the away side j more goals.
+func (m GoalModel) Distribution(score models.ScoreSnapshot) [][]float64 {
+	return m.DistributionBetween(score.Minute, m.length())
+}
+
+// DistributionBetween is Distribution for the goals scored between two
+// match minutes.
+func (m GoalModel) DistributionBetween(from, to int) [][]float64 {
+	share := float64(max(to-from, 0)) / float64(m.length())
+
+	home := poisson(m.HomeRate * share)
+	away := poisson(m.AwayRate * share)
+
+	matrix := make([][]float64, maxGoals+1)
+	for i := range matrix {
+		matrix[i] = make([]float64, maxGoals+1)
+		for j := range matrix[i] {
+			matrix[i][j] = home[i] * away[j]
+		}
+	}
+	return matrix
+}
+
+// Probability sums the chances of every final score accepted by outcome.
+func (m GoalModel) Probability(score models.ScoreSnapshot, outcome func(home, away int) bool) float64 {
+	return m.ProbabilityUntil(score, m.length(), outcome)
+}
+
+// ProbabilityUntil sums the chances of every score at the given minute
+// accepted by outcome.
+func (m GoalModel) ProbabilityUntil(score models.ScoreSnapshot, minute int, outcome func(home, away int) bool) float64 {
+	matrix := m.DistributionBetween(score.Minute, minute)
+
+	p := 0.0
+	for i := range matrix {
+		for j := range matrix[i] {
+			if outcome(score.Team1Score+i, score.Team2Score+j) {
+				p += matrix[i][j]
+			}
+		}
+	}
+	return p
+}
+
+// poissonAtLeast returns the chance of at least n more events when rate are
+// expected.
+func poissonAtLeast(rate float64, n int) float64 {
+	if n <= 0 {
+		return 1
+	}
+
+	term := math.Exp(-rate)
+	below := term
+	for k := 1; k < n; k++ {
+		term *= rate / float64(k)
+		below += term
+	}
+	return math.Max(0, 1-below)
+}
+
+func poisson(rate float64) []float64 {
+	probs := make([]float64, maxGoals+1)
+	probs[0] = math.Exp(-rate)
+	for k := 1; k <= maxGoals; k++ {
+		probs[k] = probs[k-1] * rate / float64(k)
+	}
+
+	sum := 0.0
+	for _, p := range probs {
+		sum += p
+	}
+	for k := range probs {
+		probs[k] /= sum
+	}
+	return probs
+}
+
+// FairCoefficient turns a probability into decimal odds without margin.
+func FairCoefficient(probability float64) float64 {
+	if probability <= 1/MaxCoefficient {
+		return MaxCoefficient
+	}
+	return roundCoefficient(1 / probability)
+}
+
+func roundCoefficient(coefficient float64) float64 {
+	coefficient = math.Round(coefficient*coefficientStep) / coefficientStep
+	if coefficient < MinCoefficient {
+		return MinCoefficient
+	}
+	if coefficient > MaxCoefficient {
+		return MaxCoefficient
+	}
+	return coefficient
+}
@@ -0,0 +1,130 @@
+package markets
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"math"
+	"testing"
+)
+
+func price(t *testing.T, marketCode, priceCode string, score models.ScoreSnapshot, model GoalModel) float64 {
+	t.Helper()
+
+	calculator, exists := Lookup(marketCode)
+	if !exists {
+		t.Fatalf("no calculator for market %s", marketCode)
+	}
+	coefficient, err := calculator.Price(marketCode, priceCode, score, model)
+	if err != nil {
+		t.Fatalf("price %s %s: %v", marketCode, priceCode, err)
+	}
+	return coefficient
+}
+
+func TestFairProbabilitiesSumToOneAtKickOff(t *testing.T) {
+	model := NewGoalModel(80, 70)
+	kickOff := models.ScoreSnapshot{}
+
+	tests := []struct {
+		market string
+		prices []string
+	}{
+		{"1X2", []string{"1", "X", "2"}},
+		{"BTTS", []string{"BTTS_Y", "BTTS_N"}},
+		{"OU5", []string{"O5", "U5"}},
+		{"OU25", []string{"O25", "U25"}},
+		{"OU45", []string{"O45", "U45"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.market, func(t *testing.T) {
+			implied := 0.0
+			for _, priceCode := range tt.prices {
+				implied += 1 / price(t, tt.market, priceCode, kickOff, model)
+			}
+			// Coefficients are rounded to two decimals, so the implied
+			// probabilities only add up to 1 within that rounding.
+			if math.Abs(implied-1) > 0.01 {
+				t.Errorf("implied probabilities sum to %.4f, want 1", implied)
+			}
+		})
+	}
+
+	outcomes := []func(home, away int) bool{
+		func(home, away int) bool { return home > away },
+		func(home, away int) bool { return home == away },
+		func(home, away int) bool { return home < away },
+	}
+	total := 0.0
+	for _, outcome := range outcomes {
+		total += model.Probability(kickOff, outcome)
+	}
+	if math.Abs(total-1) > 1e-9 {
+		t.Errorf("1X2 probabilities sum to %v, want 1", total)
+	}
+}
+
+func TestSettledOutcomesClampToLimits(t *testing.T) {
+	model := NewGoalModel(80, 70)
+	threeNil := models.ScoreSnapshot{Team1Score: 3, Total: 3, Minute: 60}
+	bothScored := models.ScoreSnapshot{Team1Score: 1, Team2Score: 1, Total: 2, Minute: 30}
+
+	tests := []struct {
+		name   string
+		market string
+		price  string
+		score  models.ScoreSnapshot
+		want   float64
+	}{
+		{"over 2.5 at 3-0", "OU25", "O25", threeNil, MinCoefficient},
+		{"under 2.5 at 3-0", "OU25", "U25", threeNil, MaxCoefficient},
+		{"btts yes at 1-1", "BTTS", "BTTS_Y", bothScored, MinCoefficient},
+		{"btts no at 1-1", "BTTS", "BTTS_N", bothScored, MaxCoefficient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := price(t, tt.market, tt.price, tt.score, model); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCoefficientsMoveAsTimePasses(t *testing.T) {
+	model := NewGoalModel(80, 70)
+
+	tests := []struct {
+		name    string
+		market  string
+		price   string
+		score   models.ScoreSnapshot
+		shorter bool
+	}{
+		{"leader shortens", "1X2", "1", models.ScoreSnapshot{Team1Score: 1, Total: 1}, true},
+		{"trailer drifts", "1X2", "2", models.ScoreSnapshot{Team1Score: 1, Total: 1}, false},
+		{"under shortens", "OU25", "U25", models.ScoreSnapshot{}, true},
+		{"over drifts", "OU25", "O25", models.ScoreSnapshot{}, false},
+		{"btts no shortens", "BTTS", "BTTS_N", models.ScoreSnapshot{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := 0.0
+			for _, minute := range []int{0, 30, 60, 85} {
+				score := tt.score
+				score.Minute = minute
+				coefficient := price(t, tt.market, tt.price, score, model)
+
+				if minute > 0 {
+					if tt.shorter && coefficient >= previous {
+						t.Errorf("minute %d: coefficient %v did not shorten from %v", minute, coefficient, previous)
+					}
+					if !tt.shorter && coefficient <= previous {
+						t.Errorf("minute %d: coefficient %v did not drift from %v", minute, coefficient, previous)
+					}
+				}
+				previous = coefficient
+			}
+		})
+	}
+}
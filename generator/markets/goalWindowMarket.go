@@ -0,0 +1,165 @@
+package markets
+
+import (
+	"fmt"
+	"github.com/VaheMuradyan/Live2/db/models"
+	"strconv"
+	"strings"
+)
+
+const goalWindowMinutes = 10
+
+// goalWindowMarket offers "goal between minute X and Y", both minutes
+// included. Market codes carry the window, as in "GW1_10"; after every goal
+// the generator opens the window that starts the minute after it. Price
+// codes add "_Y" or "_N".
+type goalWindowMarket struct{}
+
+func init() {
+	Register(goalWindowMarket{})
+}
+
+func (goalWindowMarket) MarketCodes() []string {
+	return []string{"GW1_10"}
+}
+
+func (goalWindowMarket) Owns(marketCode string) bool {
+	_, _, ok := goalWindow(marketCode)
+	return ok
+}
+
+func (m goalWindowMarket) NextInstance(score models.ScoreSnapshot) (MarketDefinition, bool) {
+	if len(score.Goals) == 0 {
+		return MarketDefinition{}, false
+	}
+
+	from := score.Goals[len(score.Goals)-1].Minute + 1
+	if from > MatchMinutes {
+		return MarketDefinition{}, false
+	}
+	return m.definition(from, len(score.Goals)+1), true
+}
+
+func (m goalWindowMarket) Definitions() []MarketDefinition {
+	return []MarketDefinition{m.definition(1, 1)}
+}
+
+func (goalWindowMarket) definition(from, instance int) MarketDefinition {
+	to := min(from+goalWindowMinutes-1, MatchMinutes)
+
+	code := fmt.Sprintf("GW%d_%d", from, to)
+	name := fmt.Sprintf("Goal Between Minute %d And %d", from, to)
+	if instance > 1 {
+		name = fmt.Sprintf("%s #%d", name, instance)
+	}
+
+	return MarketDefinition{
+		Code:           code,
+		Name:           name,
+		CollectionCode: "GOAL_TIMING",
+		CollectionName: "Goal Timing",
+		Prices: []PriceDefinition{
+			{Code: code + "_Y", Name: name + " Yes"},
+			{Code: code + "_N", Name: name + " No"},
+		},
+	}
+}
+
+func (goalWindowMarket) Price(marketCode, priceCode string, score models.ScoreSnapshot, model GoalModel) (float64, error) {
+	from, to, ok := goalWindow(marketCode)
+	if !ok {
+		return 0, unknownPrice(marketCode, priceCode)
+	}
+
+	yes := 0.0
+	if goalBetween(score, from, to) {
+		yes = 1
+	} else if score.Minute < to {
+		minutes := to - max(score.Minute, from-1)
+		yes = poissonAtLeast((model.HomeRate+model.AwayRate)*float64(minutes)/MatchMinutes, 1)
+	}
+
+	switch priceCode {
+	case marketCode + "_Y":
+		return FairCoefficient(yes), nil
+	case marketCode + "_N":
+		return FairCoefficient(1 - yes), nil
+	}
+	return 0, unknownPrice(marketCode, priceCode)
+}
+
+func (goalWindowMarket) ClosedPrices(marketCode string, score models.ScoreSnapshot) []string {
+	from, to, ok := goalWindow(marketCode)
+	if !ok {
+		return nil
+	}
+
+	if goalBetween(score, from, to) || windowOver(score, to) {
+		return []string{marketCode + "_Y", marketCode + "_N"}
+	}
+	return nil
+}
+
+func (goalWindowMarket) Settle(marketCode, priceCode string, final models.ScoreSnapshot) (Outcome, error) {
+	from, to, ok := goalWindow(marketCode)
+	if !ok {
+		return OutcomeVoid, unknownPrice(marketCode, priceCode)
+	}
+
+	scored := goalBetween(final, from, to)
+
+	switch priceCode {
+	case marketCode + "_Y":
+		return outcomeOf(scored), nil
+	case marketCode + "_N":
+		return outcomeOf(!scored), nil
+	}
+	return OutcomeVoid, unknownPrice(marketCode, priceCode)
+}
+
+func goalWindow(marketCode string) (int, int, bool) {
+	rest, ok := strings.CutPrefix(marketCode, "GW")
+	if !ok {
+		return 0, 0, false
+	}
+
+	fromText, toText, ok := strings.Cut(rest, "_")
+	if !ok {
+		return 0, 0, false
+	}
+
+	from, err := strconv.Atoi(fromText)
+	if err != nil {
+		return 0, 0, false
+	}
+	to, err := strconv.Atoi(toText)
+	if err != nil || from < 1 || to < from {
+		return 0, 0, false
+	}
+	return from, to, true
+}
+
+// windowOver reports whether a window ending at the given minute is past.
+// Windows ending at the close of a half also take in its stoppage time.
+func windowOver(score models.ScoreSnapshot, to int) bool {
+	if score.Minute != to {
+		return score.Minute > to
+	}
+
+	switch to {
+	case HalfMinutes:
+		return score.Period != models.PeriodFirstHalf
+	case MatchMinutes:
+		return score.Period == models.PeriodFullTime
+	}
+	return true
+}
+
+func goalBetween(score models.ScoreSnapshot, from, to int) bool {
+	for _, goal := range score.Goals {
+		if goal.Minute >= from && goal.Minute <= to {
+			return true
+		}
+	}
+	return false
+}
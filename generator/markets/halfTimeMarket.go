@@ -0,0 +1,243 @@
+package markets
+
+import (
+	"fmt"
+	"github.com/VaheMuradyan/Live2/db/models"
+	"strings"
+)
+
+var (
+	halfTimeResults     = []string{"1", "X", "2"}
+	halfTimeResultNames = map[string]string{"1": "Team 1", "X": "Draw", "2": "Team 2"}
+	firstHalfLines      = map[string]float64{"5": 0.5, "15": 1.5}
+)
+
+// halfTimeMarket prices the first-half result (1H1X2), first-half totals
+// (1HOU5, 1HOU15) and the half-time/full-time double (HTFT). First-half
+// markets are settled as soon as the half-time whistle goes; HTFT stays
+// open until full time.
+type halfTimeMarket struct{}
+
+func init() {
+	Register(halfTimeMarket{})
+}
+
+func (halfTimeMarket) MarketCodes() []string {
+	return []string{"1H1X2", "1HOU5", "1HOU15", "HTFT"}
+}
+
+func (halfTimeMarket) SettlesAtHalfTime(marketCode string) bool {
+	return marketCode != "HTFT"
+}
+
+func (halfTimeMarket) Definitions() []MarketDefinition {
+	definitions := []MarketDefinition{{
+		Code: "1H1X2",
+		Name: "First Half Result",
+		Prices: []PriceDefinition{
+			{Code: "1H_1", Name: "First Half Team 1"},
+			{Code: "1H_X", Name: "First Half Draw"},
+			{Code: "1H_2", Name: "First Half Team 2"},
+		},
+	}}
+
+	for _, suffix := range []string{"5", "15"} {
+		line := fmt.Sprintf("%.1f", firstHalfLines[suffix])
+		definitions = append(definitions, MarketDefinition{
+			Code: "1HOU" + suffix,
+			Name: "First Half Total Goals " + line,
+			Prices: []PriceDefinition{
+				{Code: "1HO" + suffix, Name: "First Half Over " + line},
+				{Code: "1HU" + suffix, Name: "First Half Under " + line},
+			},
+		})
+	}
+
+	htft := MarketDefinition{Code: "HTFT", Name: "Half Time/Full Time"}
+	for _, half := range halfTimeResults {
+		for _, full := range halfTimeResults {
+			htft.Prices = append(htft.Prices, PriceDefinition{
+				Code: "HTFT_" + half + "_" + full,
+				Name: halfTimeResultNames[half] + "/" + halfTimeResultNames[full],
+			})
+		}
+	}
+	definitions = append(definitions, htft)
+
+	for i := range definitions {
+		definitions[i].CollectionCode = "HALF"
+		definitions[i].CollectionName = "Half Time"
+	}
+	return definitions
+}
+
+func (m halfTimeMarket) Price(marketCode, priceCode string, score models.ScoreSnapshot, model GoalModel) (float64, error) {
+	switch marketCode {
+	case "1H1X2":
+		result, ok := strings.CutPrefix(priceCode, "1H_")
+		if _, known := halfTimeResultNames[result]; !ok || !known {
+			return 0, unknownPrice(marketCode, priceCode)
+		}
+		if firstHalfOver(score) {
+			return FairCoefficient(probabilityOf(resultOf(score.HalfTimeTeam1Score, score.HalfTimeTeam2Score) == result)), nil
+		}
+		return FairCoefficient(model.ProbabilityUntil(score, HalfMinutes, func(home, away int) bool {
+			return resultOf(home, away) == result
+		})), nil
+	case "1HOU5", "1HOU15":
+		suffix := marketCode[len("1HOU"):]
+		line := firstHalfLines[suffix]
+
+		var over float64
+		if firstHalfOver(score) {
+			over = probabilityOf(float64(score.HalfTimeTeam1Score+score.HalfTimeTeam2Score) > line)
+		} else {
+			over = model.ProbabilityUntil(score, HalfMinutes, func(home, away int) bool { return float64(home+away) > line })
+		}
+
+		switch priceCode {
+		case "1HO" + suffix:
+			return FairCoefficient(over), nil
+		case "1HU" + suffix:
+			return FairCoefficient(1 - over), nil
+		}
+	case "HTFT":
+		half, full, ok := splitHalfTimeFullTime(priceCode)
+		if !ok {
+			return 0, unknownPrice(marketCode, priceCode)
+		}
+		return FairCoefficient(m.halfTimeFullTime(half, full, score, model)), nil
+	}
+	return 0, unknownPrice(marketCode, priceCode)
+}
+
+// halfTimeFullTime combines the goals still to come in the first half with
+// those of the whole second half while the first half is being played, and
+// falls back to the full-time probability once the half-time score is known.
+func (halfTimeMarket) halfTimeFullTime(half, full string, score models.ScoreSnapshot, model GoalModel) float64 {
+	if firstHalfOver(score) {
+		if resultOf(score.HalfTimeTeam1Score, score.HalfTimeTeam2Score) != half {
+			return 0
+		}
+		return model.Probability(score, func(home, away int) bool { return resultOf(home, away) == full })
+	}
+
+	firstHalf := model.DistributionBetween(score.Minute, HalfMinutes)
+	secondHalf := model.DistributionBetween(HalfMinutes, MatchMinutes)
+
+	p := 0.0
+	for i := range firstHalf {
+		for j := range firstHalf[i] {
+			home, away := score.Team1Score+i, score.Team2Score+j
+			if resultOf(home, away) != half {
+				continue
+			}
+			for k := range secondHalf {
+				for l := range secondHalf[k] {
+					if resultOf(home+k, away+l) == full {
+						p += firstHalf[i][j] * secondHalf[k][l]
+					}
+				}
+			}
+		}
+	}
+	return p
+}
+
+func (m halfTimeMarket) ClosedPrices(marketCode string, score models.ScoreSnapshot) []string {
+	switch marketCode {
+	case "1H1X2":
+		if firstHalfOver(score) {
+			return []string{"1H_1", "1H_X", "1H_2"}
+		}
+	case "1HOU5", "1HOU15":
+		suffix := marketCode[len("1HOU"):]
+		if firstHalfOver(score) || float64(score.Total) > firstHalfLines[suffix] {
+			return []string{"1HO" + suffix, "1HU" + suffix}
+		}
+	case "HTFT":
+		if !firstHalfOver(score) {
+			return nil
+		}
+
+		var closed []string
+		halfTime := resultOf(score.HalfTimeTeam1Score, score.HalfTimeTeam2Score)
+		for _, half := range halfTimeResults {
+			if half == halfTime {
+				continue
+			}
+			for _, full := range halfTimeResults {
+				closed = append(closed, "HTFT_"+half+"_"+full)
+			}
+		}
+		return closed
+	}
+	return nil
+}
+
+func (halfTimeMarket) Settle(marketCode, priceCode string, final models.ScoreSnapshot) (Outcome, error) {
+	halfTime := resultOf(final.HalfTimeTeam1Score, final.HalfTimeTeam2Score)
+
+	switch marketCode {
+	case "1H1X2":
+		if result, ok := strings.CutPrefix(priceCode, "1H_"); ok {
+			if _, known := halfTimeResultNames[result]; known {
+				return outcomeOf(halfTime == result), nil
+			}
+		}
+	case "1HOU5", "1HOU15":
+		suffix := marketCode[len("1HOU"):]
+		over := float64(final.HalfTimeTeam1Score+final.HalfTimeTeam2Score) > firstHalfLines[suffix]
+
+		switch priceCode {
+		case "1HO" + suffix:
+			return outcomeOf(over), nil
+		case "1HU" + suffix:
+			return outcomeOf(!over), nil
+		}
+	case "HTFT":
+		if half, full, ok := splitHalfTimeFullTime(priceCode); ok {
+			return outcomeOf(half == halfTime && full == resultOf(final.Team1Score, final.Team2Score)), nil
+		}
+	}
+	return OutcomeVoid, unknownPrice(marketCode, priceCode)
+}
+
+func splitHalfTimeFullTime(priceCode string) (string, string, bool) {
+	parts := strings.Split(priceCode, "_")
+	if len(parts) != 3 || parts[0] != "HTFT" {
+		return "", "", false
+	}
+	if _, ok := halfTimeResultNames[parts[1]]; !ok {
+		return "", "", false
+	}
+	if _, ok := halfTimeResultNames[parts[2]]; !ok {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+func firstHalfOver(score models.ScoreSnapshot) bool {
+	switch score.Period {
+	case models.PeriodHalfTime, models.PeriodSecondHalf, models.PeriodFullTime:
+		return true
+	}
+	return false
+}
+
+func resultOf(home, away int) string {
+	switch {
+	case home > away:
+		return "1"
+	case home < away:
+		return "2"
+	}
+	return "X"
+}
+
+func probabilityOf(happened bool) float64 {
+	if happened {
+		return 1
+	}
+	return 0
+}
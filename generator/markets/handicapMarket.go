@@ -0,0 +1,241 @@
+package markets
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type asianHandicapMarket struct {
+	codes []string
+	lines map[string]float64
+}
+
+type europeanHandicapMarket struct {
+	codes []string
+	lines map[string]float64
+}
+
+func init() {
+	asian := asianHandicapMarket{lines: make(map[string]float64)}
+	for quarter := -10; quarter <= 10; quarter++ {
+		line := float64(quarter) / 4
+		code := "AH" + handicapSuffix(line)
+		asian.codes = append(asian.codes, code)
+		asian.lines[code] = line
+	}
+	Register(asian)
+
+	european := europeanHandicapMarket{lines: make(map[string]float64)}
+	for _, line := range []float64{-2, -1, 1, 2} {
+		code := "EH" + handicapSuffix(line)
+		european.codes = append(european.codes, code)
+		european.lines[code] = line
+	}
+	Register(european)
+}
+
+// handicapSuffix encodes a home handicap into a market code suffix, e.g.
+// -0.75 becomes "M075" and +1.5 becomes "P15".
+func handicapSuffix(line float64) string {
+	digits := strings.ReplaceAll(strconv.FormatFloat(math.Abs(line), 'f', -1, 64), ".", "")
+
+	switch {
+	case line < 0:
+		return "M" + digits
+	case line > 0:
+		return "P" + digits
+	}
+	return digits
+}
+
+func handicapName(line float64) string {
+	return strconv.FormatFloat(line, 'f', -1, 64)
+}
+
+func (m asianHandicapMarket) MarketCodes() []string {
+	return m.codes
+}
+
+func (m asianHandicapMarket) Definitions() []MarketDefinition {
+	var definitions []MarketDefinition
+
+	for _, code := range m.codes {
+		name := "Asian Handicap " + handicapName(m.lines[code])
+		definitions = append(definitions, MarketDefinition{
+			Code:           code,
+			Name:           name,
+			CollectionCode: "HANDICAP",
+			CollectionName: "Handicap",
+			Prices: []PriceDefinition{
+				{Code: code + "_1", Name: name + " Home"},
+				{Code: code + "_2", Name: name + " Away"},
+			},
+		})
+	}
+
+	return definitions
+}
+
+func (m asianHandicapMarket) Price(marketCode, priceCode string, score models.ScoreSnapshot, model GoalModel) (float64, error) {
+	line, side, err := m.parse(marketCode, priceCode)
+	if err != nil {
+		return 0, err
+	}
+
+	win, lose := 0.0, 0.0
+	for _, part := range splitHandicap(line) {
+		win += model.Probability(score, func(home, away int) bool { return handicapResult(home, away, part, side) > 0 })
+		lose += model.Probability(score, func(home, away int) bool { return handicapResult(home, away, part, side) < 0 })
+	}
+
+	if win <= 0 {
+		return MaxCoefficient, nil
+	}
+	return roundCoefficient(1 + lose/win), nil
+}
+
+func (m asianHandicapMarket) ClosedPrices(marketCode string, score models.ScoreSnapshot) []string {
+	return nil
+}
+
+func (m asianHandicapMarket) Settle(marketCode, priceCode string, final models.ScoreSnapshot) (Outcome, error) {
+	line, side, err := m.parse(marketCode, priceCode)
+	if err != nil {
+		return OutcomeVoid, err
+	}
+
+	parts := splitHandicap(line)
+	result := 0
+	for _, part := range parts {
+		result += handicapResult(final.Team1Score, final.Team2Score, part, side)
+	}
+
+	if len(parts) == 1 {
+		switch {
+		case result > 0:
+			return OutcomeWon, nil
+		case result < 0:
+			return OutcomeLost, nil
+		}
+		return OutcomePush, nil
+	}
+
+	switch result {
+	case 2:
+		return OutcomeWon, nil
+	case 1:
+		return OutcomeHalfWon, nil
+	case -1:
+		return OutcomeHalfLost, nil
+	case -2:
+		return OutcomeLost, nil
+	}
+	return OutcomePush, nil
+}
+
+func (m asianHandicapMarket) parse(marketCode, priceCode string) (float64, int, error) {
+	line, ok := m.lines[marketCode]
+	if !ok {
+		return 0, 0, unknownPrice(marketCode, priceCode)
+	}
+
+	switch priceCode {
+	case marketCode + "_1":
+		return line, 1, nil
+	case marketCode + "_2":
+		return line, -1, nil
+	}
+	return 0, 0, unknownPrice(marketCode, priceCode)
+}
+
+// splitHandicap splits a quarter line into the two lines that share the
+// stake; half and whole lines are returned as they are.
+func splitHandicap(line float64) []float64 {
+	if math.Mod(math.Abs(line)*4, 2) == 1 {
+		return []float64{line - 0.25, line + 0.25}
+	}
+	return []float64{line}
+}
+
+// handicapResult returns 1, 0 or -1 when the side wins, pushes or loses on a
+// single home handicap line. Side is 1 for the home team and -1 for the away
+// team, who receives the opposite handicap.
+func handicapResult(home, away int, line float64, side int) int {
+	adjusted := float64(side) * (float64(home-away) + line)
+
+	switch {
+	case adjusted > 0:
+		return 1
+	case adjusted < 0:
+		return -1
+	}
+	return 0
+}
+
+func (m europeanHandicapMarket) MarketCodes() []string {
+	return m.codes
+}
+
+func (m europeanHandicapMarket) Definitions() []MarketDefinition {
+	var definitions []MarketDefinition
+
+	for _, code := range m.codes {
+		name := "European Handicap " + handicapName(m.lines[code])
+		definitions = append(definitions, MarketDefinition{
+			Code:           code,
+			Name:           name,
+			CollectionCode: "HANDICAP",
+			CollectionName: "Handicap",
+			Prices: []PriceDefinition{
+				{Code: code + "_1", Name: name + " Home"},
+				{Code: code + "_X", Name: name + " Draw"},
+				{Code: code + "_2", Name: name + " Away"},
+			},
+		})
+	}
+
+	return definitions
+}
+
+func (m europeanHandicapMarket) Price(marketCode, priceCode string, score models.ScoreSnapshot, model GoalModel) (float64, error) {
+	line, result, err := m.parse(marketCode, priceCode)
+	if err != nil {
+		return 0, err
+	}
+
+	return FairCoefficient(model.Probability(score, func(home, away int) bool {
+		return handicapResult(home, away, line, 1) == result
+	})), nil
+}
+
+func (m europeanHandicapMarket) ClosedPrices(marketCode string, score models.ScoreSnapshot) []string {
+	return nil
+}
+
+func (m europeanHandicapMarket) Settle(marketCode, priceCode string, final models.ScoreSnapshot) (Outcome, error) {
+	line, result, err := m.parse(marketCode, priceCode)
+	if err != nil {
+		return OutcomeVoid, err
+	}
+
+	return outcomeOf(handicapResult(final.Team1Score, final.Team2Score, line, 1) == result), nil
+}
+
+func (m europeanHandicapMarket) parse(marketCode, priceCode string) (float64, int, error) {
+	line, ok := m.lines[marketCode]
+	if !ok {
+		return 0, 0, unknownPrice(marketCode, priceCode)
+	}
+
+	switch priceCode {
+	case marketCode + "_1":
+		return line, 1, nil
+	case marketCode + "_X":
+		return line, 0, nil
+	case marketCode + "_2":
+		return line, -1, nil
+	}
+	return 0, 0, unknownPrice(marketCode, priceCode)
+}
@@ -0,0 +1,93 @@
+package markets
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"testing"
+)
+
+func settle(t *testing.T, marketCode, priceCode string, home, away int) Outcome {
+	t.Helper()
+
+	calculator, exists := Lookup(marketCode)
+	if !exists {
+		t.Fatalf("no calculator for market %s", marketCode)
+	}
+	outcome, err := calculator.Settle(marketCode, priceCode, models.ScoreSnapshot{Team1Score: home, Team2Score: away, Total: home + away})
+	if err != nil {
+		t.Fatalf("settle %s %s: %v", marketCode, priceCode, err)
+	}
+	return outcome
+}
+
+func TestAsianHandicapSettlement(t *testing.T) {
+	tests := []struct {
+		name      string
+		priceCode string
+		home      int
+		away      int
+		want      Outcome
+	}{
+		{"-0.75 home wins by two", "AHM075_1", 2, 0, OutcomeWon},
+		{"-0.75 home wins by one", "AHM075_1", 1, 0, OutcomeHalfWon},
+		{"-0.75 away when home wins by one", "AHM075_2", 1, 0, OutcomeHalfLost},
+		{"-0.75 home draws", "AHM075_1", 1, 1, OutcomeLost},
+		{"-0.75 away when draw", "AHM075_2", 1, 1, OutcomeWon},
+
+		{"-1 home wins by two", "AHM1_1", 3, 1, OutcomeWon},
+		{"-1 home wins by one", "AHM1_1", 1, 0, OutcomePush},
+		{"-1 away when home wins by one", "AHM1_2", 1, 0, OutcomePush},
+		{"-1 home draws", "AHM1_1", 0, 0, OutcomeLost},
+
+		{"-1.5 home wins by two", "AHM15_1", 2, 0, OutcomeWon},
+		{"-1.5 home wins by one", "AHM15_1", 2, 1, OutcomeLost},
+		{"-1.5 away when home wins by one", "AHM15_2", 2, 1, OutcomeWon},
+
+		{"+0.25 home wins", "AHP025_1", 1, 0, OutcomeWon},
+		{"+0.25 home draws", "AHP025_1", 2, 2, OutcomeHalfWon},
+		{"+0.25 away when draw", "AHP025_2", 2, 2, OutcomeHalfLost},
+		{"+0.25 home loses", "AHP025_1", 0, 1, OutcomeLost},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			marketCode := tt.priceCode[:len(tt.priceCode)-2]
+			if got := settle(t, marketCode, tt.priceCode, tt.home, tt.away); got != tt.want {
+				t.Fatalf("%s at %d-%d settled %s, want %s", tt.priceCode, tt.home, tt.away, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEuropeanHandicapSettlement(t *testing.T) {
+	tests := []struct {
+		marketCode string
+		home       int
+		away       int
+		winner     string
+	}{
+		{"EHM1", 2, 0, "_1"},
+		{"EHM1", 1, 0, "_X"},
+		{"EHM1", 1, 1, "_2"},
+		{"EHM2", 3, 0, "_1"},
+		{"EHM2", 2, 0, "_X"},
+		{"EHM2", 1, 0, "_2"},
+		{"EHP1", 0, 0, "_1"},
+		{"EHP1", 0, 1, "_X"},
+		{"EHP1", 0, 2, "_2"},
+		{"EHP2", 0, 1, "_1"},
+		{"EHP2", 0, 2, "_X"},
+		{"EHP2", 1, 4, "_2"},
+	}
+
+	for _, tt := range tests {
+		for _, side := range []string{"_1", "_X", "_2"} {
+			want := OutcomeLost
+			if side == tt.winner {
+				want = OutcomeWon
+			}
+			if got := settle(t, tt.marketCode, tt.marketCode+side, tt.home, tt.away); got != want {
+				t.Errorf("%s%s at %d-%d settled %s, want %s", tt.marketCode, side, tt.home, tt.away, got, want)
+			}
+		}
+	}
+}
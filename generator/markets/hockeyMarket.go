@@ -0,0 +1,135 @@
+package markets
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+)
+
+const (
+	HockeyMinutes       = 60
+	HockeyPeriodMinutes = HockeyMinutes / 3
+
+	hockeyGoalRate    = 3.0
+	hockeyAdvantage   = 1.05
+	hockeyRatingScale = 0.015
+)
+
+func NewHockeyModel(homeRating, awayRating int) GoalModel {
+	return newRatedModel(homeRating, awayRating, hockeyGoalRate, hockeyAdvantage, hockeyRatingScale, HockeyMinutes)
+}
+
+// hockeyMarket prices the moneyline (HML), overtime and shootout included,
+// and the puck lines HPLM15 (home -1.5) and HPLP15 (home +1.5). Prices add
+// "_1" for the home side and "_2" for the away side.
+type hockeyMarket struct{}
+
+func init() {
+	Register(hockeyMarket{})
+}
+
+func (hockeyMarket) Sport() string {
+	return SportHockey
+}
+
+func (hockeyMarket) MarketCodes() []string {
+	return []string{"HML", "HPLM15", "HPLP15"}
+}
+
+func (m hockeyMarket) Definitions() []MarketDefinition {
+	names := map[string]string{
+		"HML":    "Hockey Moneyline",
+		"HPLM15": "Hockey Puck Line -1.5",
+		"HPLP15": "Hockey Puck Line +1.5",
+	}
+
+	var definitions []MarketDefinition
+	for _, code := range m.MarketCodes() {
+		definitions = append(definitions, MarketDefinition{
+			Code:           code,
+			Name:           names[code],
+			CollectionCode: "HOCKEY",
+			CollectionName: "Hockey",
+			Prices: []PriceDefinition{
+				{Code: code + "_1", Name: names[code] + " Team 1"},
+				{Code: code + "_2", Name: names[code] + " Team 2"},
+			},
+		})
+	}
+	return definitions
+}
+
+func (m hockeyMarket) Price(marketCode, priceCode string, score models.ScoreSnapshot, model GoalModel) (float64, error) {
+	if !m.offers(marketCode) {
+		return 0, unknownPrice(marketCode, priceCode)
+	}
+
+	var home float64
+
+	if score.Period == models.PeriodOvertime || score.Period == models.PeriodShootout {
+		// Overtime is sudden death, so the game ends one goal apart.
+		switch marketCode {
+		case "HML":
+			home = model.HomeShare()
+		case "HPLM15":
+			home = 0
+		case "HPLP15":
+			home = 1
+		}
+	} else {
+		switch marketCode {
+		case "HML":
+			home = model.Probability(score, func(home, away int) bool { return home > away }) +
+				model.Probability(score, func(home, away int) bool { return home == away })*model.HomeShare()
+		case "HPLM15":
+			home = model.Probability(score, func(home, away int) bool { return home-away >= 2 })
+		case "HPLP15":
+			home = model.Probability(score, func(home, away int) bool { return home-away >= -1 })
+		}
+	}
+
+	switch priceCode {
+	case marketCode + "_1":
+		return FairCoefficient(home), nil
+	case marketCode + "_2":
+		return FairCoefficient(1 - home), nil
+	}
+	return 0, unknownPrice(marketCode, priceCode)
+}
+
+func (hockeyMarket) ClosedPrices(marketCode string, score models.ScoreSnapshot) []string {
+	return nil
+}
+
+func (m hockeyMarket) Settle(marketCode, priceCode string, final models.ScoreSnapshot) (Outcome, error) {
+	if !m.offers(marketCode) {
+		return OutcomeVoid, unknownPrice(marketCode, priceCode)
+	}
+
+	margin := float64(final.Team1Score - final.Team2Score)
+
+	var home bool
+	switch marketCode {
+	case "HML":
+		home = margin > 0
+	case "HPLM15":
+		home = margin-1.5 > 0
+	case "HPLP15":
+		home = margin+1.5 > 0
+	}
+
+	switch priceCode {
+	case marketCode + "_1":
+		return outcomeOf(home), nil
+	case marketCode + "_2":
+		return outcomeOf(!home), nil
+	}
+	return OutcomeVoid, unknownPrice(marketCode, priceCode)
+}
+
+func (m hockeyMarket) offers(marketCode string) bool {
+	for _, code := range m.MarketCodes() {
+		if code == marketCode {
+			return true
+		}
+	}
+	return false
+}
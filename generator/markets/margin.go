@@ -0,0 +1,37 @@
+package markets
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+)
+
+const DefaultMargin = 0.05
+
+// MarketMargin resolves the target overround of a market: its own margin
+// wins over the margin of its collection, which wins over DefaultMargin.
+func MarketMargin(market models.Market) float64 {
+	if market.Margin > 0 {
+		return market.Margin
+	}
+	if market.MarketCollection.Margin > 0 {
+		return market.MarketCollection.Margin
+	}
+	return DefaultMargin
+}
+
+// ApplyMargin scales the implied probabilities of a market's fair
+// coefficients so that they add up to 1 + margin. It expects the outcomes
+// still open under the market's ClosedPrices rule, however short or long
+// they are priced; settled ones are left out by the caller.
+func ApplyMargin(fair []float64, margin float64) []float64 {
+	total := 0.0
+	for _, coefficient := range fair {
+		total += 1 / coefficient
+	}
+
+	margined := make([]float64, len(fair))
+	for i, coefficient := range fair {
+		probability := (1 / coefficient) / total * (1 + margin)
+		margined[i] = roundCoefficient(1 / probability)
+	}
+	return margined
+}
@@ -0,0 +1,67 @@
+package markets
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"math"
+	"testing"
+)
+
+func impliedTotal(coefficients []float64) float64 {
+	total := 0.0
+	for _, coefficient := range coefficients {
+		total += 1 / coefficient
+	}
+	return total
+}
+
+func TestApplyMarginReachesOverround(t *testing.T) {
+	tests := []struct {
+		name   string
+		fair   []float64
+		margin float64
+	}{
+		{"two-way", []float64{1.8, 2.25}, 0.05},
+		{"three-way", []float64{2.1, 3.4, 3.9}, 0.08},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := impliedTotal(ApplyMargin(tt.fair, tt.margin))
+
+			if math.Abs(got-(1+tt.margin)) > 0.01 {
+				t.Errorf("implied probabilities sum to %.4f, want %.4f", got, 1+tt.margin)
+			}
+		})
+	}
+}
+
+func TestApplyMarginKeepsStrongFavouritesInTheBook(t *testing.T) {
+	margined := ApplyMargin([]float64{MinCoefficient, 101}, 0.05)
+
+	if margined[0] != MinCoefficient {
+		t.Errorf("favourite priced at %v, want %v", margined[0], MinCoefficient)
+	}
+	if want := 101 / 1.05; math.Abs(margined[1]-want) > 0.01 {
+		t.Errorf("outsider priced at %v, want the margin taken off it, %.2f", margined[1], want)
+	}
+}
+
+func TestMarketMarginOverridesCollection(t *testing.T) {
+	tests := []struct {
+		name   string
+		market models.Market
+		want   float64
+	}{
+		{"market wins", models.Market{Margin: 0.07, MarketCollection: models.MarketCollection{Margin: 0.1}}, 0.07},
+		{"collection fallback", models.Market{MarketCollection: models.MarketCollection{Margin: 0.1}}, 0.1},
+		{"default", models.Market{}, DefaultMargin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MarketMargin(tt.market); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
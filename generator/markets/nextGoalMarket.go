@@ -0,0 +1,122 @@
+package markets
+
+import (
+	"fmt"
+	"github.com/VaheMuradyan/Live2/db/models"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// nextGoalMarket offers "team to score the next goal". The first instance
+// is NG1; after every goal the generator opens NG2, NG3 and so on. Price
+// codes look like "NG2_1", "NG2_N" (no further goal) and "NG2_2".
+type nextGoalMarket struct{}
+
+func init() {
+	Register(nextGoalMarket{})
+}
+
+func (nextGoalMarket) MarketCodes() []string {
+	return []string{"NG1"}
+}
+
+func (nextGoalMarket) Owns(marketCode string) bool {
+	_, ok := nextGoalInstance(marketCode)
+	return ok
+}
+
+func (m nextGoalMarket) NextInstance(score models.ScoreSnapshot) (MarketDefinition, bool) {
+	return m.definition(score.Total + 1), true
+}
+
+func (m nextGoalMarket) Definitions() []MarketDefinition {
+	return []MarketDefinition{m.definition(1)}
+}
+
+func (nextGoalMarket) definition(instance int) MarketDefinition {
+	code := "NG" + strconv.Itoa(instance)
+	name := fmt.Sprintf("Next Goal #%d", instance)
+
+	return MarketDefinition{
+		Code:           code,
+		Name:           name,
+		CollectionCode: "GOAL_TIMING",
+		CollectionName: "Goal Timing",
+		Prices: []PriceDefinition{
+			{Code: code + "_1", Name: name + " Team 1"},
+			{Code: code + "_N", Name: name + " No Goal"},
+			{Code: code + "_2", Name: name + " Team 2"},
+		},
+	}
+}
+
+func (nextGoalMarket) Price(marketCode, priceCode string, score models.ScoreSnapshot, model GoalModel) (float64, error) {
+	instance, ok := nextGoalInstance(marketCode)
+	if !ok {
+		return 0, unknownPrice(marketCode, priceCode)
+	}
+
+	var home, none, away float64
+	if len(score.Goals) >= instance {
+		home = probabilityOf(score.Goals[instance-1].Team == 1)
+		away = 1 - home
+	} else {
+		left := remainingShare(score.Minute)
+		none = math.Exp(-(model.HomeRate + model.AwayRate) * left)
+		home = (1 - none) * model.HomeShare()
+		away = 1 - none - home
+	}
+
+	switch priceCode {
+	case marketCode + "_1":
+		return FairCoefficient(home), nil
+	case marketCode + "_N":
+		return FairCoefficient(none), nil
+	case marketCode + "_2":
+		return FairCoefficient(away), nil
+	}
+	return 0, unknownPrice(marketCode, priceCode)
+}
+
+func (nextGoalMarket) ClosedPrices(marketCode string, score models.ScoreSnapshot) []string {
+	if instance, ok := nextGoalInstance(marketCode); ok && score.Total >= instance {
+		return []string{marketCode + "_1", marketCode + "_N", marketCode + "_2"}
+	}
+	return nil
+}
+
+func (nextGoalMarket) Settle(marketCode, priceCode string, final models.ScoreSnapshot) (Outcome, error) {
+	instance, ok := nextGoalInstance(marketCode)
+	if !ok {
+		return OutcomeVoid, unknownPrice(marketCode, priceCode)
+	}
+
+	scorer := 0
+	if len(final.Goals) >= instance {
+		scorer = final.Goals[instance-1].Team
+	}
+
+	switch priceCode {
+	case marketCode + "_1":
+		return outcomeOf(scorer == 1), nil
+	case marketCode + "_N":
+		return outcomeOf(scorer == 0), nil
+	case marketCode + "_2":
+		return outcomeOf(scorer == 2), nil
+	}
+	return OutcomeVoid, unknownPrice(marketCode, priceCode)
+}
+
+func nextGoalInstance(marketCode string) (int, bool) {
+	rest, ok := strings.CutPrefix(marketCode, "NG")
+	if !ok {
+		return 0, false
+	}
+
+	instance, err := strconv.Atoi(rest)
+	if err != nil || instance < 1 {
+		return 0, false
+	}
+	return instance, true
+}
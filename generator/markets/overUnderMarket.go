@@ -2,63 +2,69 @@ package markets
 
 import (
 	"github.com/VaheMuradyan/Live2/db/models"
-	"math/rand"
 )
 
-func CalculateOverUnderCoefficient(priceCode string, score models.ScoreSnapshot) float64 {
-	currentTotal := float64(score.Total)
+type overUnderMarket struct {
+	lines map[string]float64
+}
+
+func init() {
+	Register(overUnderMarket{lines: map[string]float64{
+		"5":  0.5,
+		"15": 1.5,
+		"25": 2.5,
+		"35": 3.5,
+		"45": 4.5,
+	}})
+}
+
+func (m overUnderMarket) MarketCodes() []string {
+	return []string{"OU5", "OU15", "OU25", "OU35", "OU45"}
+}
+
+func (m overUnderMarket) Price(marketCode, priceCode string, score models.ScoreSnapshot, model GoalModel) (float64, error) {
+	suffix := marketCode[len("OU"):]
+
+	line, ok := m.lines[suffix]
+	if !ok {
+		return 0, unknownPrice(marketCode, priceCode)
+	}
+
+	over := model.Probability(score, func(home, away int) bool { return float64(home+away) > line })
+
+	switch priceCode {
+	case "O" + suffix:
+		return FairCoefficient(over), nil
+	case "U" + suffix:
+		return FairCoefficient(1 - over), nil
+	}
+	return 0, unknownPrice(marketCode, priceCode)
+}
+
+func (m overUnderMarket) ClosedPrices(marketCode string, score models.ScoreSnapshot) []string {
+	suffix := marketCode[len("OU"):]
+
+	if line, ok := m.lines[suffix]; ok && float64(score.Total) > line {
+		return []string{"O" + suffix, "U" + suffix}
+	}
+	return nil
+}
+
+func (m overUnderMarket) Settle(marketCode, priceCode string, final models.ScoreSnapshot) (Outcome, error) {
+	suffix := marketCode[len("OU"):]
+
+	line, ok := m.lines[suffix]
+	if !ok {
+		return OutcomeVoid, unknownPrice(marketCode, priceCode)
+	}
+
+	over := float64(final.Team1Score+final.Team2Score) > line
 
 	switch priceCode {
-	case "O5":
-		if currentTotal > 0.5 {
-			return 1.1 + float64(rand.Intn(30))/100
-		}
-		return 1.8 + float64(rand.Intn(60))/100
-	case "U5":
-		if currentTotal > 0.5 {
-			return 2.5 + float64(rand.Intn(150))/100
-		}
-		return 1.6 + float64(rand.Intn(40))/100
-	case "O15":
-		if currentTotal > 1.5 {
-			return 1.1 + float64(rand.Intn(30))/100
-		}
-		return 1.8 + float64(rand.Intn(60))/100
-	case "U15":
-		if currentTotal > 1.5 {
-			return 2.5 + float64(rand.Intn(150))/100
-		}
-		return 1.6 + float64(rand.Intn(40))/100
-	case "O25":
-		if currentTotal > 2.5 {
-			return 1.1 + float64(rand.Intn(30))/100
-		}
-		return 1.8 + float64(rand.Intn(60))/100
-	case "U25":
-		if currentTotal > 2.5 {
-			return 2.5 + float64(rand.Intn(150))/100
-		}
-		return 1.6 + float64(rand.Intn(40))/100
-	case "O35":
-		if currentTotal > 3.5 {
-			return 1.1 + float64(rand.Intn(30))/100
-		}
-		return 1.8 + float64(rand.Intn(60))/100
-	case "U35":
-		if currentTotal > 3.5 {
-			return 2.5 + float64(rand.Intn(150))/100
-		}
-		return 1.6 + float64(rand.Intn(40))/100
-	case "O45":
-		if currentTotal > 4.5 {
-			return 1.1 + float64(rand.Intn(30))/100
-		}
-		return 1.8 + float64(rand.Intn(60))/100
-	case "U45":
-		if currentTotal > 4.5 {
-			return 2.5 + float64(rand.Intn(150))/100
-		}
-		return 1.6 + float64(rand.Intn(40))/100
+	case "O" + suffix:
+		return outcomeOf(over), nil
+	case "U" + suffix:
+		return outcomeOf(!over), nil
 	}
-	return 1.9
+	return OutcomeVoid, unknownPrice(marketCode, priceCode)
 }
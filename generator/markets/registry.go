@@ -0,0 +1,130 @@
+package markets
+
+import (
+	"fmt"
+	"github.com/VaheMuradyan/Live2/db/models"
+)
+
+// MarketCalculator prices the markets it registers for, tells which of their
+// prices can no longer be offered for a given score and resolves them once
+// the final score is known.
+type MarketCalculator interface {
+	MarketCodes() []string
+	Price(marketCode, priceCode string, score models.ScoreSnapshot, model GoalModel) (float64, error)
+	ClosedPrices(marketCode string, score models.ScoreSnapshot) []string
+	Settle(marketCode, priceCode string, final models.ScoreSnapshot) (Outcome, error)
+}
+
+// MarketFamily is implemented by calculators whose markets are reopened
+// under a new instance code while the event runs, such as Next Goal #2, #3.
+// Owns reports whether a market code is one of the family's instances and
+// NextInstance describes the instance that should be open for the current
+// score, so asking again before the score moves on gives the same one.
+type MarketFamily interface {
+	Owns(marketCode string) bool
+	NextInstance(score models.ScoreSnapshot) (MarketDefinition, bool)
+}
+
+var (
+	registry      = make(map[string]MarketCalculator)
+	registryCodes []string
+	families      []MarketCalculator
+)
+
+func Register(calculator MarketCalculator) {
+	for _, code := range calculator.MarketCodes() {
+		if _, exists := registry[code]; exists {
+			panic(fmt.Sprintf("markets: calculator for market %s registered twice", code))
+		}
+		registry[code] = calculator
+		registryCodes = append(registryCodes, code)
+	}
+
+	if _, ok := calculator.(MarketFamily); ok {
+		families = append(families, calculator)
+	}
+}
+
+func Lookup(marketCode string) (MarketCalculator, bool) {
+	if calculator, exists := registry[marketCode]; exists {
+		return calculator, true
+	}
+
+	for _, calculator := range families {
+		if calculator.(MarketFamily).Owns(marketCode) {
+			return calculator, true
+		}
+	}
+	return nil, false
+}
+
+func Families() []MarketFamily {
+	result := make([]MarketFamily, len(families))
+	for i, calculator := range families {
+		result[i] = calculator.(MarketFamily)
+	}
+	return result
+}
+
+func Codes() []string {
+	codes := make([]string, len(registryCodes))
+	copy(codes, registryCodes)
+	return codes
+}
+
+func unknownPrice(marketCode, priceCode string) error {
+	return fmt.Errorf("unknown price %s in market %s", priceCode, marketCode)
+}
+
+// MarketDefinition describes the Market and Price rows a calculator needs
+// in the database.
+type MarketDefinition struct {
+	Sport          string
+	Code           string
+	Name           string
+	CollectionCode string
+	CollectionName string
+	Prices         []PriceDefinition
+}
+
+type PriceDefinition struct {
+	Code string
+	Name string
+}
+
+// SeededMarket is implemented by calculators whose Market and Price rows are
+// created on demand instead of being shipped with the database.
+type SeededMarket interface {
+	Definitions() []MarketDefinition
+}
+
+// HalfTimeMarket is implemented by calculators whose markets are decided by
+// the half-time score and can be settled before the match ends.
+type HalfTimeMarket interface {
+	SettlesAtHalfTime(marketCode string) bool
+}
+
+func SettlesAtHalfTime(marketCode string) bool {
+	calculator, _ := Lookup(marketCode)
+	halfTime, ok := calculator.(HalfTimeMarket)
+	return ok && halfTime.SettlesAtHalfTime(marketCode)
+}
+
+func Definitions(marketCodes []string) []MarketDefinition {
+	var definitions []MarketDefinition
+
+	for _, code := range marketCodes {
+		seeded, ok := registry[code].(SeededMarket)
+		if !ok {
+			continue
+		}
+		for _, definition := range seeded.Definitions() {
+			if definition.Code == code {
+				definition.Sport = MarketSport(code)
+				definitions = append(definitions, definition)
+			}
+		}
+	}
+
+	return definitions
+}
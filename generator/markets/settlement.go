@@ -0,0 +1,19 @@
+package markets
+
+type Outcome string
+
+const (
+	OutcomeWon      Outcome = "won"
+	OutcomeLost     Outcome = "lost"
+	OutcomeVoid     Outcome = "void"
+	OutcomePush     Outcome = "push"
+	OutcomeHalfWon  Outcome = "half_won"
+	OutcomeHalfLost Outcome = "half_lost"
+)
+
+func outcomeOf(won bool) Outcome {
+	if won {
+		return OutcomeWon
+	}
+	return OutcomeLost
+}
@@ -0,0 +1,62 @@
+package markets
+
+import (
+	"strings"
+)
+
+const (
+	SportFootball   = "football"
+	SportBasketball = "basketball"
+	SportTennis     = "tennis"
+	SportHockey     = "hockey"
+)
+
+var sportAliases = map[string]string{
+	"soccer":     SportFootball,
+	"ice_hockey": SportHockey,
+	"ice-hockey": SportHockey,
+	"icehockey":  SportHockey,
+}
+
+// NormalizeSport maps a Sport.Code to one of the simulated sports. Unknown
+// and empty codes are treated as football.
+func NormalizeSport(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if alias, ok := sportAliases[code]; ok {
+		return alias
+	}
+
+	switch code {
+	case SportBasketball, SportTennis, SportHockey:
+		return code
+	}
+	return SportFootball
+}
+
+// NewSportModel builds the pricing model for a sport from the ratings of
+// both sides.
+func NewSportModel(sport string, homeRating, awayRating int) GoalModel {
+	switch sport {
+	case SportBasketball:
+		return NewBasketballModel(homeRating, awayRating)
+	case SportHockey:
+		return NewHockeyModel(homeRating, awayRating)
+	case SportTennis:
+		return NewTennisModel(homeRating, awayRating)
+	}
+	return NewGoalModel(homeRating, awayRating)
+}
+
+// SportMarket is implemented by calculators for sports other than football.
+type SportMarket interface {
+	Sport() string
+}
+
+// MarketSport returns the sport a market is priced for.
+func MarketSport(marketCode string) string {
+	calculator, _ := Lookup(marketCode)
+	if sportMarket, ok := calculator.(SportMarket); ok {
+		return sportMarket.Sport()
+	}
+	return SportFootball
+}
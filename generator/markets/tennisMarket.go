@@ -0,0 +1,425 @@
+package markets
+
+import (
+	"fmt"
+	"github.com/VaheMuradyan/Live2/db/models"
+	"strconv"
+	"strings"
+)
+
+// setScores are the final scores in sets of a best-of-three match, as used
+// in the set betting price codes TSB_2_0 to TSB_0_2.
+var setScores = [][2]int{{2, 0}, {2, 1}, {1, 2}, {0, 2}}
+
+// tennisMarket prices the match winner (TML, prices TML_1 and TML_2) and
+// set betting (TSB) from the set score distribution of a best-of-three
+// match.
+type tennisMarket struct{}
+
+func init() {
+	Register(tennisMarket{})
+}
+
+func (tennisMarket) Sport() string {
+	return SportTennis
+}
+
+func (tennisMarket) MarketCodes() []string {
+	return []string{"TML", "TSB"}
+}
+
+func (tennisMarket) Definitions() []MarketDefinition {
+	setBetting := MarketDefinition{
+		Code:           "TSB",
+		Name:           "Tennis Set Betting",
+		CollectionCode: "TENNIS",
+		CollectionName: "Tennis",
+	}
+	for _, sets := range setScores {
+		setBetting.Prices = append(setBetting.Prices, PriceDefinition{
+			Code: setScoreCode(sets),
+			Name: fmt.Sprintf("Tennis Set Betting %d-%d", sets[0], sets[1]),
+		})
+	}
+
+	return []MarketDefinition{
+		{
+			Code:           "TML",
+			Name:           "Tennis Match Winner",
+			CollectionCode: "TENNIS",
+			CollectionName: "Tennis",
+			Prices: []PriceDefinition{
+				{Code: "TML_1", Name: "Tennis Match Winner Player 1"},
+				{Code: "TML_2", Name: "Tennis Match Winner Player 2"},
+			},
+		},
+		setBetting,
+	}
+}
+
+func (tennisMarket) Price(marketCode, priceCode string, score models.ScoreSnapshot, model GoalModel) (float64, error) {
+	distribution := model.SetScoreDistribution(score)
+
+	switch marketCode {
+	case "TML":
+		home := 0.0
+		for sets, p := range distribution {
+			if sets[0] == SetsToWin {
+				home += p
+			}
+		}
+
+		switch priceCode {
+		case "TML_1":
+			return FairCoefficient(home), nil
+		case "TML_2":
+			return FairCoefficient(1 - home), nil
+		}
+	case "TSB":
+		for _, sets := range setScores {
+			if priceCode == setScoreCode(sets) {
+				return FairCoefficient(distribution[sets]), nil
+			}
+		}
+	}
+	return 0, unknownPrice(marketCode, priceCode)
+}
+
+func (tennisMarket) ClosedPrices(marketCode string, score models.ScoreSnapshot) []string {
+	if marketCode != "TSB" {
+		return nil
+	}
+
+	var closed []string
+	for _, sets := range setScores {
+		if score.Team1Score > sets[0] || score.Team2Score > sets[1] {
+			closed = append(closed, setScoreCode(sets))
+		}
+	}
+	return closed
+}
+
+func (tennisMarket) Settle(marketCode, priceCode string, final models.ScoreSnapshot) (Outcome, error) {
+	switch marketCode {
+	case "TML":
+		switch priceCode {
+		case "TML_1":
+			return outcomeOf(final.Team1Score > final.Team2Score), nil
+		case "TML_2":
+			return outcomeOf(final.Team1Score < final.Team2Score), nil
+		}
+	case "TSB":
+		for _, sets := range setScores {
+			if priceCode == setScoreCode(sets) {
+				return outcomeOf(final.Team1Score == sets[0] && final.Team2Score == sets[1]), nil
+			}
+		}
+	}
+	return OutcomeVoid, unknownPrice(marketCode, priceCode)
+}
+
+func setScoreCode(sets [2]int) string {
+	return fmt.Sprintf("TSB_%d_%d", sets[0], sets[1])
+}
+
+// tennisSetWinnerMarket prices the winner of one set. TSW1 is seeded with
+// the match and TSW2, TSW3 are opened as each set starts; prices add "_1"
+// or "_2".
+type tennisSetWinnerMarket struct{}
+
+// tennisNextGameMarket prices the winner of one game of the match,
+// counted from the first game: TNG1 is seeded and the next instance is
+// opened as each game starts.
+type tennisNextGameMarket struct{}
+
+// tennisTotalGamesMarket prices over/under lines on the games of the whole
+// match, such as TTG225 with prices TTGO225 and TTGU225 for 22.5 games.
+type tennisTotalGamesMarket struct {
+	codes []string
+	lines map[string]float64
+}
+
+func init() {
+	Register(tennisSetWinnerMarket{})
+	Register(tennisNextGameMarket{})
+
+	totals := tennisTotalGamesMarket{lines: make(map[string]float64)}
+	for _, line := range []float64{19.5, 21.5, 23.5} {
+		code := "TTG" + strings.ReplaceAll(strconv.FormatFloat(line, 'f', -1, 64), ".", "")
+		totals.codes = append(totals.codes, code)
+		totals.lines[code] = line
+	}
+	Register(totals)
+}
+
+func (tennisSetWinnerMarket) Sport() string {
+	return SportTennis
+}
+
+func (tennisSetWinnerMarket) MarketCodes() []string {
+	return []string{"TSW1"}
+}
+
+func (tennisSetWinnerMarket) Owns(marketCode string) bool {
+	_, ok := tennisInstance(marketCode, "TSW")
+	return ok
+}
+
+func (m tennisSetWinnerMarket) NextInstance(score models.ScoreSnapshot) (MarketDefinition, bool) {
+	if score.Period == models.PeriodFullTime || len(score.Periods) == 0 {
+		return MarketDefinition{}, false
+	}
+	return m.definition(len(score.Periods)), true
+}
+
+func (m tennisSetWinnerMarket) Definitions() []MarketDefinition {
+	return []MarketDefinition{m.definition(1)}
+}
+
+func (tennisSetWinnerMarket) definition(set int) MarketDefinition {
+	return playerMarketDefinition(fmt.Sprintf("TSW%d", set), fmt.Sprintf("Tennis Set %d Winner", set))
+}
+
+func (tennisSetWinnerMarket) Price(marketCode, priceCode string, score models.ScoreSnapshot, model GoalModel) (float64, error) {
+	set, ok := tennisInstance(marketCode, "TSW")
+	if !ok {
+		return 0, unknownPrice(marketCode, priceCode)
+	}
+
+	var home float64
+	switch {
+	case set < len(score.Periods) || (set == len(score.Periods) && score.Period == models.PeriodFullTime):
+		home = probabilityOf(SetWinner(score.Periods[set-1]) == 1)
+	case set == len(score.Periods):
+		home = model.currentSetOutcome(score).won(1)
+	default:
+		home = model.futureSetOutcome().won(1)
+	}
+
+	return playerCoefficient(marketCode, priceCode, home)
+}
+
+func (tennisSetWinnerMarket) ClosedPrices(marketCode string, score models.ScoreSnapshot) []string {
+	set, ok := tennisInstance(marketCode, "TSW")
+	if !ok {
+		return nil
+	}
+
+	if set < len(score.Periods) || score.Period == models.PeriodFullTime {
+		return []string{marketCode + "_1", marketCode + "_2"}
+	}
+	return nil
+}
+
+func (tennisSetWinnerMarket) Settle(marketCode, priceCode string, final models.ScoreSnapshot) (Outcome, error) {
+	set, ok := tennisInstance(marketCode, "TSW")
+	if !ok {
+		return OutcomeVoid, unknownPrice(marketCode, priceCode)
+	}
+
+	winner := 0
+	if set <= len(final.Periods) {
+		winner = SetWinner(final.Periods[set-1])
+	}
+	return playerOutcome(marketCode, priceCode, winner)
+}
+
+func (tennisNextGameMarket) Sport() string {
+	return SportTennis
+}
+
+func (tennisNextGameMarket) MarketCodes() []string {
+	return []string{"TNG1"}
+}
+
+func (tennisNextGameMarket) Owns(marketCode string) bool {
+	_, ok := tennisInstance(marketCode, "TNG")
+	return ok
+}
+
+func (m tennisNextGameMarket) NextInstance(score models.ScoreSnapshot) (MarketDefinition, bool) {
+	if score.Period == models.PeriodFullTime || score.Tennis == nil {
+		return MarketDefinition{}, false
+	}
+	return m.definition(len(score.Tennis.GameWinners) + 1), true
+}
+
+func (m tennisNextGameMarket) Definitions() []MarketDefinition {
+	return []MarketDefinition{m.definition(1)}
+}
+
+func (tennisNextGameMarket) definition(game int) MarketDefinition {
+	return playerMarketDefinition(fmt.Sprintf("TNG%d", game), fmt.Sprintf("Tennis Game %d Winner", game))
+}
+
+func (tennisNextGameMarket) Price(marketCode, priceCode string, score models.ScoreSnapshot, model GoalModel) (float64, error) {
+	game, ok := tennisInstance(marketCode, "TNG")
+	if !ok {
+		return 0, unknownPrice(marketCode, priceCode)
+	}
+
+	var home float64
+	switch {
+	case score.Tennis == nil:
+		home = (model.GameProbability(1) + model.GameProbability(2)) / 2
+	case game <= len(score.Tennis.GameWinners):
+		home = probabilityOf(score.Tennis.GameWinners[game-1] == 1)
+	case game == len(score.Tennis.GameWinners)+1:
+		home = model.currentGameProbability(score)
+	default:
+		home = (model.GameProbability(1) + model.GameProbability(2)) / 2
+	}
+
+	return playerCoefficient(marketCode, priceCode, home)
+}
+
+func (tennisNextGameMarket) ClosedPrices(marketCode string, score models.ScoreSnapshot) []string {
+	game, ok := tennisInstance(marketCode, "TNG")
+	if !ok || score.Tennis == nil {
+		return nil
+	}
+
+	if game <= len(score.Tennis.GameWinners) || score.Period == models.PeriodFullTime {
+		return []string{marketCode + "_1", marketCode + "_2"}
+	}
+	return nil
+}
+
+func (tennisNextGameMarket) Settle(marketCode, priceCode string, final models.ScoreSnapshot) (Outcome, error) {
+	game, ok := tennisInstance(marketCode, "TNG")
+	if !ok {
+		return OutcomeVoid, unknownPrice(marketCode, priceCode)
+	}
+
+	winner := 0
+	if final.Tennis != nil && game <= len(final.Tennis.GameWinners) {
+		winner = final.Tennis.GameWinners[game-1]
+	}
+	return playerOutcome(marketCode, priceCode, winner)
+}
+
+func (tennisTotalGamesMarket) Sport() string {
+	return SportTennis
+}
+
+func (m tennisTotalGamesMarket) MarketCodes() []string {
+	return m.codes
+}
+
+func (m tennisTotalGamesMarket) Definitions() []MarketDefinition {
+	var definitions []MarketDefinition
+
+	for _, code := range m.codes {
+		suffix := code[len("TTG"):]
+		line := strconv.FormatFloat(m.lines[code], 'f', -1, 64)
+
+		definitions = append(definitions, MarketDefinition{
+			Code:           code,
+			Name:           "Tennis Total Games " + line,
+			CollectionCode: "TENNIS",
+			CollectionName: "Tennis",
+			Prices: []PriceDefinition{
+				{Code: "TTGO" + suffix, Name: "Tennis Over " + line + " Games"},
+				{Code: "TTGU" + suffix, Name: "Tennis Under " + line + " Games"},
+			},
+		})
+	}
+	return definitions
+}
+
+func (m tennisTotalGamesMarket) Price(marketCode, priceCode string, score models.ScoreSnapshot, model GoalModel) (float64, error) {
+	line, ok := m.lines[marketCode]
+	if !ok {
+		return 0, unknownPrice(marketCode, priceCode)
+	}
+
+	over := 0.0
+	for games, p := range model.TotalGamesDistribution(score) {
+		if float64(games) > line {
+			over += p
+		}
+	}
+
+	suffix := marketCode[len("TTG"):]
+	switch priceCode {
+	case "TTGO" + suffix:
+		return FairCoefficient(over), nil
+	case "TTGU" + suffix:
+		return FairCoefficient(1 - over), nil
+	}
+	return 0, unknownPrice(marketCode, priceCode)
+}
+
+func (m tennisTotalGamesMarket) ClosedPrices(marketCode string, score models.ScoreSnapshot) []string {
+	if line, ok := m.lines[marketCode]; ok && float64(score.Total) > line {
+		suffix := marketCode[len("TTG"):]
+		return []string{"TTGO" + suffix, "TTGU" + suffix}
+	}
+	return nil
+}
+
+func (m tennisTotalGamesMarket) Settle(marketCode, priceCode string, final models.ScoreSnapshot) (Outcome, error) {
+	line, ok := m.lines[marketCode]
+	if !ok {
+		return OutcomeVoid, unknownPrice(marketCode, priceCode)
+	}
+
+	over := float64(final.Total) > line
+
+	suffix := marketCode[len("TTG"):]
+	switch priceCode {
+	case "TTGO" + suffix:
+		return outcomeOf(over), nil
+	case "TTGU" + suffix:
+		return outcomeOf(!over), nil
+	}
+	return OutcomeVoid, unknownPrice(marketCode, priceCode)
+}
+
+func playerMarketDefinition(code, name string) MarketDefinition {
+	return MarketDefinition{
+		Code:           code,
+		Name:           name,
+		CollectionCode: "TENNIS",
+		CollectionName: "Tennis",
+		Prices: []PriceDefinition{
+			{Code: code + "_1", Name: name + " Player 1"},
+			{Code: code + "_2", Name: name + " Player 2"},
+		},
+	}
+}
+
+func playerCoefficient(marketCode, priceCode string, home float64) (float64, error) {
+	switch priceCode {
+	case marketCode + "_1":
+		return FairCoefficient(home), nil
+	case marketCode + "_2":
+		return FairCoefficient(1 - home), nil
+	}
+	return 0, unknownPrice(marketCode, priceCode)
+}
+
+// playerOutcome settles a two-player market; a winner of 0 means the set or
+// game was never played and voids the price.
+func playerOutcome(marketCode, priceCode string, winner int) (Outcome, error) {
+	if priceCode != marketCode+"_1" && priceCode != marketCode+"_2" {
+		return OutcomeVoid, unknownPrice(marketCode, priceCode)
+	}
+	if winner == 0 {
+		return OutcomeVoid, nil
+	}
+	return outcomeOf(priceCode == fmt.Sprintf("%s_%d", marketCode, winner)), nil
+}
+
+func tennisInstance(marketCode, prefix string) (int, bool) {
+	rest, ok := strings.CutPrefix(marketCode, prefix)
+	if !ok {
+		return 0, false
+	}
+
+	instance, err := strconv.Atoi(rest)
+	if err != nil || instance < 1 {
+		return 0, false
+	}
+	return instance, true
+}
@@ -0,0 +1,237 @@
+package markets
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"math"
+)
+
+const (
+	SetsToWin = 2
+
+	baseServeProbability = 0.64
+	tennisRatingScale    = 0.004
+	minServeProbability  = 0.45
+	maxServeProbability  = 0.85
+	gamesPerSet          = 6
+	pointsPerGame        = 4
+	pointsPerTieBreak    = 7
+)
+
+// NewTennisModel derives the chance of each player winning a point on their
+// own serve from the difference of their ratings.
+func NewTennisModel(homeRating, awayRating int) GoalModel {
+	diff := float64(homeRating - awayRating)
+
+	return GoalModel{
+		HomeServe: clampServe(baseServeProbability + tennisRatingScale*diff),
+		AwayServe: clampServe(baseServeProbability - tennisRatingScale*diff),
+	}
+}
+
+func clampServe(p float64) float64 {
+	return math.Min(maxServeProbability, math.Max(minServeProbability, p))
+}
+
+// PointProbability is the chance of the home player winning a point served
+// by the given player.
+func (m GoalModel) PointProbability(server int) float64 {
+	if server == 2 {
+		return 1 - m.AwayServe
+	}
+	return m.HomeServe
+}
+
+// GameProbability is the chance of the home player winning a game served by
+// the given player.
+func (m GoalModel) GameProbability(server int) float64 {
+	return raceProbability(m.PointProbability(server), 0, 0, pointsPerGame)
+}
+
+// TieBreakProbability is the chance of the home player winning a tie-break,
+// where both players serve in turn.
+func (m GoalModel) TieBreakProbability() float64 {
+	p := (m.PointProbability(1) + m.PointProbability(2)) / 2
+	return raceProbability(p, 0, 0, pointsPerTieBreak)
+}
+
+// SetProbability is the chance of the home player winning a set from the
+// given games with the given player about to serve.
+func (m GoalModel) SetProbability(games1, games2, server int) float64 {
+	return m.setOutcome(games1, games2, server, -1).won(1)
+}
+
+// currentGameProbability is the chance of the home player winning the game
+// or tie-break in progress from its current points.
+func (m GoalModel) currentGameProbability(score models.ScoreSnapshot) float64 {
+	tennis := score.Tennis
+	if tennis.TieBreak {
+		p := (m.PointProbability(1) + m.PointProbability(2)) / 2
+		return raceProbability(p, tennis.Team1Points, tennis.Team2Points, pointsPerTieBreak)
+	}
+	return raceProbability(m.PointProbability(tennis.Server), tennis.Team1Points, tennis.Team2Points, pointsPerGame)
+}
+
+// setOutcome holds, for the set winner at index 1 or 2, the chance of the
+// set ending after each number of games.
+type setOutcome [3][maxSetGames + 1]float64
+
+const maxSetGames = 2*gamesPerSet + 1
+
+func (o setOutcome) won(player int) float64 {
+	total := 0.0
+	for _, p := range o[player] {
+		total += p
+	}
+	return total
+}
+
+// setOutcome plays a set out from the given games with the given player
+// about to serve. current is the home player's chance of winning the game
+// in progress, or negative when the next game has not started yet.
+func (m GoalModel) setOutcome(games1, games2, server int, current float64) setOutcome {
+	var outcome setOutcome
+
+	games := [3]float64{0, m.GameProbability(1), m.GameProbability(2)}
+	tieBreak := m.TieBreakProbability()
+
+	var walk func(games1, games2, server int, p, next float64)
+	walk = func(games1, games2, server int, p, next float64) {
+		if winner := SetWinner(models.PeriodScore{Team1: games1, Team2: games2}); winner != 0 {
+			outcome[winner][games1+games2] += p
+			return
+		}
+
+		if next < 0 {
+			next = games[server]
+			if games1 == gamesPerSet && games2 == gamesPerSet {
+				next = tieBreak
+			}
+		}
+		walk(games1+1, games2, 3-server, p*next, -1)
+		walk(games1, games2+1, 3-server, p*(1-next), -1)
+	}
+	walk(games1, games2, server, 1, current)
+
+	return outcome
+}
+
+func (m GoalModel) currentSetOutcome(score models.ScoreSnapshot) setOutcome {
+	if len(score.Periods) == 0 || score.Tennis == nil {
+		return m.futureSetOutcome()
+	}
+
+	games := score.Periods[len(score.Periods)-1]
+	return m.setOutcome(games.Team1, games.Team2, score.Tennis.Server, m.currentGameProbability(score))
+}
+
+// futureSetOutcome averages over who serves first in a set not started yet.
+func (m GoalModel) futureSetOutcome() setOutcome {
+	first, second := m.setOutcome(0, 0, 1, -1), m.setOutcome(0, 0, 2, -1)
+	for player := range first {
+		for games := range first[player] {
+			first[player][games] = (first[player][games] + second[player][games]) / 2
+		}
+	}
+	return first
+}
+
+// walkMatch calls visit with every final score in sets and total of games
+// the match can still end with, and its chance.
+func (m GoalModel) walkMatch(score models.ScoreSnapshot, visit func(sets1, sets2, games int, p float64)) {
+	if score.Period == models.PeriodFullTime {
+		visit(score.Team1Score, score.Team2Score, score.Total, 1)
+		return
+	}
+
+	played := 0
+	for _, set := range score.Periods[:max(len(score.Periods)-1, 0)] {
+		played += set.Team1 + set.Team2
+	}
+
+	future := m.futureSetOutcome()
+
+	var walk func(sets1, sets2, games int, p float64, set setOutcome)
+	walk = func(sets1, sets2, games int, p float64, set setOutcome) {
+		if sets1 == SetsToWin || sets2 == SetsToWin {
+			visit(sets1, sets2, games, p)
+			return
+		}
+
+		for winner := 1; winner <= 2; winner++ {
+			for setGames, q := range set[winner] {
+				if q == 0 {
+					continue
+				}
+				if winner == 1 {
+					walk(sets1+1, sets2, games+setGames, p*q, future)
+				} else {
+					walk(sets1, sets2+1, games+setGames, p*q, future)
+				}
+			}
+		}
+	}
+	walk(score.Team1Score, score.Team2Score, played, 1, m.currentSetOutcome(score))
+}
+
+// SetScoreDistribution gives the chance of every final score in sets,
+// keyed by the sets won by each player.
+func (m GoalModel) SetScoreDistribution(score models.ScoreSnapshot) map[[2]int]float64 {
+	distribution := make(map[[2]int]float64)
+	m.walkMatch(score, func(sets1, sets2, games int, p float64) {
+		distribution[[2]int{sets1, sets2}] += p
+	})
+	return distribution
+}
+
+// TotalGamesDistribution gives the chance of the match ending with each
+// total number of games.
+func (m GoalModel) TotalGamesDistribution(score models.ScoreSnapshot) map[int]float64 {
+	distribution := make(map[int]float64)
+	m.walkMatch(score, func(sets1, sets2, games int, p float64) {
+		distribution[games] += p
+	})
+	return distribution
+}
+
+// SetWinner returns the player who won a set with these games, or 0 while
+// it is still being played.
+func SetWinner(set models.PeriodScore) int {
+	switch {
+	case set.Team1 == gamesPerSet+1 || (set.Team1 == gamesPerSet && set.Team1-set.Team2 >= 2):
+		return 1
+	case set.Team2 == gamesPerSet+1 || (set.Team2 == gamesPerSet && set.Team2-set.Team1 >= 2):
+		return 2
+	}
+	return 0
+}
+
+// GameWinner returns the player who won a game, or a tie-break, with these
+// points, or 0 while it is still being played.
+func GameWinner(points1, points2 int, tieBreak bool) int {
+	target := pointsPerGame
+	if tieBreak {
+		target = pointsPerTieBreak
+	}
+
+	switch {
+	case points1 >= target && points1-points2 >= 2:
+		return 1
+	case points2 >= target && points2-points1 >= 2:
+		return 2
+	}
+	return 0
+}
+
+// raceProbability is the chance of winning a race to target points, by two
+// clear, from a points to b when each point is won with probability p.
+func raceProbability(p float64, a, b, target int) float64 {
+	switch {
+	case a >= target && a-b >= 2:
+		return 1
+	case b >= target && b-a >= 2:
+		return 0
+	case a >= target-1 && a == b:
+		return p * p / (p*p + (1-p)*(1-p))
+	}
+	return p*raceProbability(p, a+1, b, target) + (1-p)*raceProbability(p, a, b+1, target)
+}
@@ -0,0 +1,99 @@
+package generator
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"github.com/VaheMuradyan/Live2/generator/markets"
+	"math/rand"
+	"time"
+)
+
+const (
+	defaultSecondsPerMinute = 0.5
+	halfTimeBreakMinutes    = 15
+	maxFirstHalfStoppage    = 4
+	maxSecondHalfStoppage   = 6
+)
+
+// matchClock advances a football match one minute at a time through both
+// halves, their stoppage time and the half-time break.
+type matchClock struct {
+	period    string
+	minute    int
+	added     int
+	stoppage  map[string]int
+	breakLeft int
+}
+
+func newMatchClock(rng *rand.Rand) *matchClock {
+	return &matchClock{
+		period: models.PeriodFirstHalf,
+		stoppage: map[string]int{
+			models.PeriodFirstHalf:  rng.Intn(maxFirstHalfStoppage + 1),
+			models.PeriodSecondHalf: 1 + rng.Intn(maxSecondHalfStoppage),
+		},
+	}
+}
+
+// advance moves the clock on by one minute and reports whether the period
+// changed.
+func (c *matchClock) advance() bool {
+	switch c.period {
+	case models.PeriodFirstHalf, models.PeriodSecondHalf:
+		end := markets.HalfMinutes
+		if c.period == models.PeriodSecondHalf {
+			end = markets.MatchMinutes
+		}
+
+		if c.minute < end {
+			c.minute++
+			return false
+		}
+		if c.added < c.stoppage[c.period] {
+			c.added++
+			return false
+		}
+
+		c.added = 0
+		if c.period == models.PeriodFirstHalf {
+			c.period = models.PeriodHalfTime
+			c.breakLeft = halfTimeBreakMinutes
+		} else {
+			c.period = models.PeriodFullTime
+		}
+		return true
+	case models.PeriodHalfTime:
+		c.breakLeft--
+		if c.breakLeft > 0 {
+			return false
+		}
+		c.period = models.PeriodSecondHalf
+		return true
+	}
+	return false
+}
+
+func (c *matchClock) running() bool {
+	return c.period == models.PeriodFirstHalf || c.period == models.PeriodSecondHalf
+}
+
+func (c *matchClock) finished() bool {
+	return c.period == models.PeriodFullTime
+}
+
+func (c *matchClock) apply(scoreSnapshot *models.ScoreSnapshot) {
+	scoreSnapshot.Minute = c.minute
+	scoreSnapshot.AddedTime = c.added
+	scoreSnapshot.Period = c.period
+
+	if c.period == models.PeriodHalfTime {
+		scoreSnapshot.HalfTimeTeam1Score = scoreSnapshot.Team1Score
+		scoreSnapshot.HalfTimeTeam2Score = scoreSnapshot.Team2Score
+	}
+}
+
+func minuteInterval(secondsPerMinute float64) time.Duration {
+	if secondsPerMinute <= 0 {
+		secondsPerMinute = defaultSecondsPerMinute
+	}
+	return time.Duration(secondsPerMinute * float64(time.Second))
+}
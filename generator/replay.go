@@ -0,0 +1,338 @@
+package generator
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/VaheMuradyan/Live2/db/models"
+	"github.com/VaheMuradyan/Live2/generator/markets"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	SourceRandom = "random"
+	sourceReplay = "replay:"
+
+	// replayPeriodType marks a timeline entry that starts a new period
+	// rather than recording an incident.
+	replayPeriodType = "period"
+)
+
+var replayPeriods = map[string]int{
+	models.PeriodFirstHalf:  0,
+	models.PeriodHalfTime:   1,
+	models.PeriodSecondHalf: 2,
+	models.PeriodFullTime:   3,
+}
+
+var replayIncidentTypes = map[string]bool{
+	models.IncidentGoal:           true,
+	models.IncidentOwnGoal:        true,
+	models.IncidentPenaltyAwarded: true,
+	models.IncidentPenaltyScored:  true,
+	models.IncidentPenaltyMissed:  true,
+	models.IncidentYellowCard:     true,
+	models.IncidentRedCard:        true,
+	models.IncidentCorner:         true,
+	models.IncidentSubstitution:   true,
+	models.IncidentVARReview:      true,
+	models.IncidentGoalCancelled:  true,
+}
+
+var replayCSVHeader = []string{"event_code", "minute", "added_time", "period", "type", "team"}
+
+// Replay is a recorded football match. Its timeline starts with a "period"
+// entry for 1H, ends with one for FT and lists the incidents in between in
+// match-clock order. In JSON:
+//
+//	{"event_code": "MA", "timeline": [
+//	  {"minute": 0, "period": "1H", "type": "period"},
+//	  {"minute": 23, "period": "1H", "type": "goal", "team": 1},
+//	  ...
+//	  {"minute": 90, "added_time": 4, "period": "FT", "type": "period"}]}
+//
+// CSV files carry the same fields with the header
+// event_code,minute,added_time,period,type,team.
+type Replay struct {
+	EventCode string        `json:"event_code"`
+	Timeline  []ReplayEntry `json:"timeline"`
+}
+
+type ReplayEntry struct {
+	Minute    int    `json:"minute"`
+	AddedTime int    `json:"added_time"`
+	Period    string `json:"period"`
+	Type      string `json:"type"`
+	Team      int    `json:"team"`
+}
+
+// ParseSource reads an event source as sent in RequestData.Sources and
+// returns the replay file it names inside REPLAY_DIR, or "" for a random
+// simulation. Files outside the directory are refused.
+func ParseSource(source string) (string, error) {
+	if source == "" || source == SourceRandom {
+		return "", nil
+	}
+
+	name, ok := strings.CutPrefix(source, sourceReplay)
+	if !ok || name == "" {
+		return "", fmt.Errorf("unknown source %q, expected %q or %q", source, SourceRandom, sourceReplay+"<file>")
+	}
+
+	name = filepath.Clean(name)
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("replay %q must be a file inside the replay directory", name)
+	}
+	return filepath.Join(replayDir(), name), nil
+}
+
+func replayDir() string {
+	if dir := os.Getenv("REPLAY_DIR"); dir != "" {
+		return dir
+	}
+	return "replays"
+}
+
+// ValidateSources loads every replay file requested so a bad one is
+// reported before the simulation starts.
+func ValidateSources(sources map[string]string) error {
+	for eventCode, source := range sources {
+		path, err := ParseSource(source)
+		if err != nil {
+			return fmt.Errorf("event %s: %w", eventCode, err)
+		}
+		if path == "" {
+			continue
+		}
+		if _, err = LoadReplay(path, eventCode); err != nil {
+			return fmt.Errorf("event %s: %w", eventCode, err)
+		}
+	}
+	return nil
+}
+
+// LoadReplay reads a JSON or CSV replay file, chosen by its extension, and
+// checks that it is a valid timeline for the event.
+func LoadReplay(path, eventCode string) (*Replay, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replay: %w", err)
+	}
+	defer file.Close()
+
+	var replay *Replay
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		replay, err = readJSONReplay(file)
+	case ".csv":
+		replay, err = readCSVReplay(file)
+	default:
+		return nil, fmt.Errorf("replay %s must be a .json or .csv file", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replay %s: %w", path, err)
+	}
+
+	if replay.EventCode != eventCode {
+		return nil, fmt.Errorf("replay %s is for event %q, not %q", path, replay.EventCode, eventCode)
+	}
+	if err = replay.validate(); err != nil {
+		return nil, fmt.Errorf("invalid replay %s: %w", path, err)
+	}
+	return replay, nil
+}
+
+func readJSONReplay(r io.Reader) (*Replay, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var replay Replay
+	if err := decoder.Decode(&replay); err != nil {
+		return nil, err
+	}
+	return &replay, nil
+}
+
+func readCSVReplay(r io.Reader) (*Replay, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 || strings.Join(rows[0], ",") != strings.Join(replayCSVHeader, ",") {
+		return nil, fmt.Errorf("header must be %s", strings.Join(replayCSVHeader, ","))
+	}
+
+	replay := &Replay{}
+	for i, row := range rows[1:] {
+		line := i + 2
+
+		if replay.EventCode == "" {
+			replay.EventCode = row[0]
+		} else if row[0] != replay.EventCode {
+			return nil, fmt.Errorf("line %d: event code %q differs from %q", line, row[0], replay.EventCode)
+		}
+
+		entry := ReplayEntry{Period: row[3], Type: row[4]}
+		if entry.Minute, err = strconv.Atoi(row[1]); err != nil {
+			return nil, fmt.Errorf("line %d: invalid minute %q", line, row[1])
+		}
+		if row[2] != "" {
+			if entry.AddedTime, err = strconv.Atoi(row[2]); err != nil {
+				return nil, fmt.Errorf("line %d: invalid added time %q", line, row[2])
+			}
+		}
+		if row[5] != "" {
+			if entry.Team, err = strconv.Atoi(row[5]); err != nil {
+				return nil, fmt.Errorf("line %d: invalid team %q", line, row[5])
+			}
+		}
+
+		replay.Timeline = append(replay.Timeline, entry)
+	}
+	return replay, nil
+}
+
+func (r *Replay) validate() error {
+	if len(r.Timeline) < 2 {
+		return errors.New("timeline needs at least a 1H and an FT period entry")
+	}
+
+	first, last := r.Timeline[0], r.Timeline[len(r.Timeline)-1]
+	if first.Type != replayPeriodType || first.Period != models.PeriodFirstHalf {
+		return errors.New("timeline must start with the 1H period entry")
+	}
+	if last.Type != replayPeriodType || last.Period != models.PeriodFullTime {
+		return errors.New("timeline must end with the FT period entry")
+	}
+
+	period := first.Period
+	var previous ReplayEntry
+
+	for i, entry := range r.Timeline {
+		order, known := replayPeriods[entry.Period]
+		switch {
+		case !known:
+			return fmt.Errorf("entry %d: unknown period %q", i+1, entry.Period)
+		case entry.Minute < 0 || entry.Minute > markets.MatchMinutes || entry.AddedTime < 0:
+			return fmt.Errorf("entry %d: invalid time %d+%d", i+1, entry.Minute, entry.AddedTime)
+		case entry.Type == replayPeriodType:
+			if order < replayPeriods[period] || (i > 0 && entry.Period == period) {
+				return fmt.Errorf("entry %d: period %s cannot follow %s", i+1, entry.Period, period)
+			}
+			period = entry.Period
+		case !replayIncidentTypes[entry.Type]:
+			return fmt.Errorf("entry %d: unknown incident type %q", i+1, entry.Type)
+		case entry.Period != period:
+			return fmt.Errorf("entry %d: incident in %s while the match is in %s", i+1, entry.Period, period)
+		case period != models.PeriodFirstHalf && period != models.PeriodSecondHalf:
+			return fmt.Errorf("entry %d: incident outside play in %s", i+1, period)
+		case entry.Team != 1 && entry.Team != 2:
+			return fmt.Errorf("entry %d: team must be 1 or 2", i+1)
+		case entry.Minute < previous.Minute || (entry.Minute == previous.Minute && entry.AddedTime < previous.AddedTime):
+			return fmt.Errorf("entry %d: timeline goes back in time", i+1)
+		}
+		previous = entry
+	}
+	return nil
+}
+
+// replayMatch plays a recorded timeline back on the match clock, one
+// minute of stoppage or regular time per tick, firing each entry when the
+// clock reaches it.
+type replayMatch struct {
+	timeline []ReplayEntry
+	next     int
+	minute   int
+	added    int
+	done     bool
+}
+
+func newReplayMatch(replay *Replay) *replayMatch {
+	return &replayMatch{timeline: replay.Timeline}
+}
+
+func (r *replayMatch) start(score *models.ScoreSnapshot) {
+	score.Sport = markets.SportFootball
+	r.fire(score)
+}
+
+func (r *replayMatch) tick(score *models.ScoreSnapshot) ([]models.Incident, bool) {
+	if r.done {
+		return nil, false
+	}
+
+	entry := r.timeline[r.next]
+	switch {
+	case entry.Minute > r.minute:
+		r.minute++
+		r.added = 0
+	case entry.AddedTime > r.added:
+		r.added++
+	}
+	score.Minute = r.minute
+	score.AddedTime = r.added
+
+	return r.fire(score)
+}
+
+// fire applies the entries the clock has reached and reports whether the
+// period changed. It stops after a period entry so every period is
+// published in a snapshot of its own.
+func (r *replayMatch) fire(score *models.ScoreSnapshot) ([]models.Incident, bool) {
+	var incidents []models.Incident
+	changed := false
+
+	for !changed && r.next < len(r.timeline) && !r.ahead(r.timeline[r.next]) {
+		entry := r.timeline[r.next]
+		r.next++
+
+		if entry.Type == replayPeriodType {
+			score.Period = entry.Period
+			if entry.Period == models.PeriodHalfTime {
+				score.HalfTimeTeam1Score = score.Team1Score
+				score.HalfTimeTeam2Score = score.Team2Score
+			}
+			r.minute, r.added = entry.Minute, entry.AddedTime
+			r.done = entry.Period == models.PeriodFullTime
+			changed = true
+			continue
+		}
+
+		incident := models.Incident{
+			Type:      entry.Type,
+			Team:      entry.Team,
+			Minute:    r.minute,
+			AddedTime: r.added,
+			Period:    score.Period,
+		}
+		applyIncident(score, incident)
+		incidents = append(incidents, incident)
+	}
+
+	score.Minute = r.minute
+	score.AddedTime = r.added
+	return incidents, changed
+}
+
+// ahead reports whether the entry lies after the clock. An entry behind it,
+// such as the second half starting at minute 45 after first-half stoppage
+// time, fires at once and sets the clock back.
+func (r *replayMatch) ahead(entry ReplayEntry) bool {
+	if entry.Minute != r.minute {
+		return entry.Minute > r.minute
+	}
+	return entry.AddedTime > r.added
+}
+
+func (r *replayMatch) snapshotDue() bool {
+	return !r.done && r.added == 0 && r.minute%priceUpdateMinutes == 0
+}
+
+func (r *replayMatch) finished() bool {
+	return r.done
+}
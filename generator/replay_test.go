@@ -0,0 +1,143 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const replayJSON = `{"event_code": "MA", "timeline": [
+  {"minute": 0, "period": "1H", "type": "period"},
+  {"minute": 23, "period": "1H", "type": "goal", "team": 1},
+  {"minute": 45, "added_time": 2, "period": "HT", "type": "period"},
+  {"minute": 45, "period": "2H", "type": "period"},
+  {"minute": 70, "period": "2H", "type": "red_card", "team": 2},
+  {"minute": 90, "added_time": 4, "period": "FT", "type": "period"}]}`
+
+const replayCSV = `event_code,minute,added_time,period,type,team
+MA,0,,1H,period,
+MA,23,,1H,goal,1
+MA,45,2,HT,period,
+MA,45,,2H,period,
+MA,70,,2H,red_card,2
+MA,90,4,FT,period,
+`
+
+// writeReplays puts the files into a fresh REPLAY_DIR.
+func writeReplays(t *testing.T, files map[string]string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("REPLAY_DIR", dir)
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadReplay(t *testing.T) {
+	writeReplays(t, map[string]string{
+		"match.json": replayJSON,
+		"match.csv":  replayCSV,
+		"match.txt":  replayJSON,
+		"backwards.json": strings.Replace(replayJSON,
+			`{"minute": 70, "period": "2H", "type": "red_card", "team": 2}`,
+			`{"minute": 70, "period": "2H", "type": "red_card", "team": 2},
+  {"minute": 60, "period": "2H", "type": "corner", "team": 1}`, 1),
+		"backwards.csv":      strings.Replace(replayCSV, "MA,70,,2H,red_card,2\n", "MA,70,,2H,red_card,2\nMA,60,,2H,corner,1\n", 1),
+		"unknown.json":       strings.Replace(replayJSON, `"red_card"`, `"throw_in"`, 1),
+		"unknown.csv":        strings.Replace(replayCSV, "red_card", "throw_in", 1),
+		"unknown_field.json": strings.Replace(replayJSON, `"event_code": "MA"`, `"event_code": "MA", "venue": "x"`, 1),
+		"bad_header.csv":     strings.Replace(replayCSV, "added_time", "stoppage", 1),
+		"no_full_time.json":  strings.Replace(replayJSON, `"period": "FT", "type": "period"`, `"period": "2H", "type": "corner", "team": 1`, 1),
+		"mixed_events.csv":   strings.Replace(replayCSV, "MA,70", "MB,70", 1),
+	})
+
+	tests := []struct {
+		file      string
+		eventCode string
+		wantErr   string
+	}{
+		{"match.json", "MA", ""},
+		{"match.csv", "MA", ""},
+		{"match.json", "MB", `is for event "MA", not "MB"`},
+		{"match.csv", "MB", `is for event "MA", not "MB"`},
+		{"match.txt", "MA", "must be a .json or .csv file"},
+		{"missing.json", "MA", "failed to open replay"},
+		{"backwards.json", "MA", "goes back in time"},
+		{"backwards.csv", "MA", "goes back in time"},
+		{"unknown.json", "MA", `unknown incident type "throw_in"`},
+		{"unknown.csv", "MA", `unknown incident type "throw_in"`},
+		{"unknown_field.json", "MA", "unknown field"},
+		{"bad_header.csv", "MA", "header must be"},
+		{"no_full_time.json", "MA", "must end with the FT period entry"},
+		{"mixed_events.csv", "MA", `event code "MB" differs from "MA"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file+"/"+tt.eventCode, func(t *testing.T) {
+			path, err := ParseSource(sourceReplay + tt.file)
+			if err != nil {
+				t.Fatalf("parse source: %v", err)
+			}
+
+			replay, err := LoadReplay(path, tt.eventCode)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(replay.Timeline) != 6 {
+					t.Fatalf("timeline has %d entries, want 6", len(replay.Timeline))
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSourceStaysInReplayDir(t *testing.T) {
+	writeReplays(t, nil)
+	dir := os.Getenv("REPLAY_DIR")
+
+	tests := []struct {
+		source  string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{SourceRandom, "", false},
+		{"replay:match.json", filepath.Join(dir, "match.json"), false},
+		{"replay:2024/final.csv", filepath.Join(dir, "2024", "final.csv"), false},
+		{"replay:2024/../match.json", filepath.Join(dir, "match.json"), false},
+		{"replay:../match.json", "", true},
+		{"replay:2024/../../match.json", "", true},
+		{"replay:/etc/passwd", "", true},
+		{"replay:", "", true},
+		{"file:match.json", "", true},
+	}
+
+	for _, tt := range tests {
+		path, err := ParseSource(tt.source)
+		if (err != nil) != tt.wantErr || path != tt.want {
+			t.Errorf("ParseSource(%q) = %q, %v; want %q, error %v", tt.source, path, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateSourcesNamesTheEvent(t *testing.T) {
+	writeReplays(t, map[string]string{"match.json": replayJSON})
+
+	if err := ValidateSources(map[string]string{"MA": "replay:match.json", "MB": SourceRandom}); err != nil {
+		t.Fatalf("valid sources rejected: %v", err)
+	}
+
+	err := ValidateSources(map[string]string{"MB": "replay:match.json"})
+	if err == nil || !strings.HasPrefix(err.Error(), "event MB:") {
+		t.Fatalf("got %v, want an error for event MB", err)
+	}
+}
@@ -3,15 +3,24 @@ package generator
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/VaheMuradyan/Live2/cache"
 	"github.com/VaheMuradyan/Live2/db/models"
 	"github.com/VaheMuradyan/Live2/generator/markets"
+	amqp "github.com/rabbitmq/amqp091-go"
 	"log"
+	"reflect"
+	"sync"
+	"time"
 )
 
-func (g *Generator) startScoreMonitoring() {
-	events := g.cache.GetActiveEvents()
+// startScoreMonitoring consumes the queue of every active event and returns
+// the consumer tags, to cancel them by, and a wait group that is done once
+// their queues are drained.
+func (g *Generator) startScoreMonitoring() ([]string, *sync.WaitGroup) {
+	var consumers []string
+	var wg sync.WaitGroup
 
-	for _, event := range events {
+	for _, event := range g.cache.GetActiveEvents() {
 		queueName := fmt.Sprintf("queue%v", event.ID)
 
 		_, err := g.channel.QueueDeclare(queueName, true, false, false, false, nil)
@@ -19,119 +28,328 @@ func (g *Generator) startScoreMonitoring() {
 			log.Printf("Failed to declare queue %s: %v", queueName, err)
 		}
 
-		go g.consumeQueue(queueName)
+		messages, err := g.channel.Consume(queueName, queueName, true, false, false, false, nil)
+		if err != nil {
+			log.Printf("Failed to consume from %s: %v", queueName, err)
+			continue
+		}
+		consumers = append(consumers, queueName)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.consumeQueue(queueName, messages)
+		}()
 	}
 
-	<-g.stopChan
+	return consumers, &wg
+}
+
+// stopScoreMonitoring cancels the consumers and waits until the messages
+// already delivered to them are handled.
+func (g *Generator) stopScoreMonitoring(consumers []string, wg *sync.WaitGroup) {
 	log.Println("Stopping score monitoring...")
-	g.cache.SaveData()
+
+	for _, consumer := range consumers {
+		if err := g.channel.Cancel(consumer, false); err != nil {
+			log.Printf("Failed to cancel consumer %s: %v", consumer, err)
+		}
+	}
+	wg.Wait()
 }
 
-func (g *Generator) consumeQueue(queueName string) {
-	messages, err := g.channel.Consume(queueName, "", true, false, false, false, nil)
-	if err != nil {
-		log.Printf("Failed to consume from %s: %v", queueName, err)
+// defaultTeamRating is given to both sides of an event whose home and away
+// teams are not set.
+const defaultTeamRating = 50
+
+// repricingIncidents are the incidents that move the odds. An incident that
+// opens a suspension is priced when the event reopens instead.
+var repricingIncidents = map[string]bool{
+	models.IncidentGoal:          true,
+	models.IncidentOwnGoal:       true,
+	models.IncidentPenaltyScored: true,
+	models.IncidentRedCard:       true,
+	models.IncidentPenaltyMissed: true,
+	models.IncidentGoalCancelled: true,
+	models.IncidentYellowCard:    true,
+	models.IncidentCorner:        true,
+}
+
+// consumeQueue keeps the event state from its queue and prices each state
+// once: on the incident that produced it or on the next snapshot, and not
+// at all while a suspension is open since reopening prices the latest one.
+func (g *Generator) consumeQueue(queueName string, messages <-chan amqp.Delivery) {
+	var state, priced models.ScoreSnapshot
+	var reopenTimer *time.Timer
+	var reopen <-chan time.Time
+
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				if reopen != nil {
+					reopenTimer.Stop()
+					g.reopenEvent(state.EventID, state)
+				}
+				return
+			}
+
+			var queueMessage models.QueueMessage
+			if err := json.Unmarshal(msg.Body, &queueMessage); err != nil {
+				log.Printf("Invalid message in %s: %v", queueName, err)
+				continue
+			}
+
+			switch {
+			case queueMessage.Type == models.MessageTypeSnapshot && queueMessage.Snapshot != nil:
+				previousPeriod := state.Period
+				state = *queueMessage.Snapshot
+				if state.Period == models.PeriodHalfTime && previousPeriod != models.PeriodHalfTime {
+					g.settleHalfTime(state)
+				}
+				if reopen == nil && !reflect.DeepEqual(state, priced) {
+					g.handleScoreChange(state.EventID, state)
+					priced = state
+				}
+			case queueMessage.Type == models.MessageTypeIncident && queueMessage.Incident != nil:
+				incident := *queueMessage.Incident
+				state.EventID = incident.EventID
+				if until, suspended := g.handleIncident(&state, incident); suspended {
+					if reopenTimer != nil {
+						reopenTimer.Stop()
+					}
+					reopenTimer = time.NewTimer(time.Until(until))
+					reopen = reopenTimer.C
+				} else if reopen == nil && repricingIncidents[incident.Type] {
+					g.handleScoreChange(state.EventID, state)
+					priced = state
+				}
+			default:
+				log.Printf("Unknown message type %q in %s", queueMessage.Type, queueName)
+			}
+		case <-reopen:
+			reopen = nil
+			g.reopenEvent(state.EventID, state)
+			priced = state
+		}
 	}
+}
 
-	for msg := range messages {
-		var score models.ScoreSnapshot
-		if err := json.Unmarshal(msg.Body, &score); err != nil {
-			log.Printf("Invalid message in %s: %v", queueName, err)
-			continue
+// handleIncident applies the incident to the event state and suspends the
+// event when the incident calls for it. It returns when a suspension it
+// opened should end.
+func (g *Generator) handleIncident(state *models.ScoreSnapshot, incident models.Incident) (time.Time, bool) {
+	applyIncident(state, incident)
+
+	until, suspended := g.suspendEvent(state.EventID, incident.Type)
+	if suspended {
+		g.suspendMarkets(state.EventID, *state)
+	}
+
+	return until, suspended
+}
+
+// settleHalfTime settles the markets decided by the first half before
+// checkAndStopMarkets takes them off the board.
+func (g *Generator) settleHalfTime(halfTime models.ScoreSnapshot) {
+	var eventPrices []models.EventPrice
+	for _, eventPrice := range g.sportPrices(halfTime.EventID) {
+		if markets.SettlesAtHalfTime(eventPrice.Price.Market.Code) {
+			eventPrices = append(eventPrices, eventPrice)
 		}
-		g.handleScoreChange(score.EventID, score)
+	}
+
+	if len(eventPrices) == 0 {
+		return
+	}
+
+	if err := g.settlement.SettleEvent(halfTime, eventPrices); err != nil {
+		log.Printf("Error settling half time of event %d: %v", halfTime.EventID, err)
 	}
 }
 
 func (g *Generator) handleScoreChange(eventID uint, currentScore models.ScoreSnapshot) {
+	g.cache.SetEventScore(currentScore)
+	g.openMarketInstances(currentScore)
 	g.checkAndStopMarkets(eventID, currentScore)
 	g.sendActiveCoefficients(eventID, currentScore)
 }
 
 func (g *Generator) checkAndStopMarkets(eventID uint, scoreSnapshot models.ScoreSnapshot) {
-	totalGoals := scoreSnapshot.Total
+	var priceIDs []uint
 
-	priceCodesToDeactivate := []string{}
+	sport := g.eventSport(eventID)
 
-	if totalGoals >= 5 {
-		priceCodesToDeactivate = append(priceCodesToDeactivate, "U45", "O45")
-	}
-	if totalGoals >= 4 {
-		priceCodesToDeactivate = append(priceCodesToDeactivate, "U35", "O35")
+	for marketCode, prices := range g.cache.GetEventMarketPrices(eventID) {
+		calculator, exists := markets.Lookup(marketCode)
+		if !exists || markets.MarketSport(marketCode) != sport {
+			continue
+		}
+
+		for _, priceCode := range calculator.ClosedPrices(marketCode, scoreSnapshot) {
+			if priceID, ok := prices[priceCode]; ok {
+				priceIDs = append(priceIDs, priceID)
+			}
+		}
 	}
-	if totalGoals >= 3 {
-		priceCodesToDeactivate = append(priceCodesToDeactivate, "U25", "O25")
+
+	if len(priceIDs) == 0 {
+		return
 	}
-	if totalGoals >= 2 {
-		priceCodesToDeactivate = append(priceCodesToDeactivate, "U15", "O15")
+
+	err := g.cache.DeactivateEventPrices(eventID, priceIDs)
+	if err != nil {
+		log.Printf("Error deactivating event prices in Redis cache for event %d: %v", eventID, err)
 	}
-	if totalGoals >= 1 {
-		priceCodesToDeactivate = append(priceCodesToDeactivate, "U5", "O5")
+}
+
+// sendActiveCoefficients re-prices the event's open prices at the score and
+// announces them, only those of the given markets when any are given.
+func (g *Generator) sendActiveCoefficients(eventID uint, scoreSnapshot models.ScoreSnapshot, marketCodes ...string) {
+	eventPrices := g.sportPrices(eventID)
+	model := g.goalModel(eventID, scoreSnapshot)
+
+	onlyMarkets := make(map[string]bool, len(marketCodes))
+	for _, code := range marketCodes {
+		onlyMarkets[code] = true
 	}
 
-	if scoreSnapshot.Team1Score > 0 && scoreSnapshot.Team2Score > 0 {
-		priceCodesToDeactivate = append(priceCodesToDeactivate, "BTTS_N", "BTTS_Y")
+	var pricedCodes []string
+	marketPrices := make(map[string][]models.EventPrice)
+	previousCoefficients := make(map[uint]float64)
+
+	for _, eventPrice := range eventPrices {
+
+		if !eventPrice.Active || (len(onlyMarkets) > 0 && !onlyMarkets[eventPrice.Price.Market.Code]) {
+			continue
+		}
+
+		previousCoefficients[eventPrice.PriceID] = eventPrice.Coefficient
+
+		code := eventPrice.Price.Market.Code
+		if _, exists := marketPrices[code]; !exists {
+			pricedCodes = append(pricedCodes, code)
+		}
+		marketPrices[code] = append(marketPrices[code], eventPrice)
 	}
 
-	if len(priceCodesToDeactivate) == 0 {
-		return
+	var priced []models.EventPrice
+	var updates []cache.PriceUpdate
+
+	for _, code := range pricedCodes {
+		var prices []models.EventPrice
+		var fairCoeffs []float64
+
+		closed := make(map[string]bool)
+		if calculator, exists := markets.Lookup(code); exists {
+			for _, priceCode := range calculator.ClosedPrices(code, scoreSnapshot) {
+				closed[priceCode] = true
+			}
+		}
+
+		for _, eventPrice := range marketPrices[code] {
+			if closed[eventPrice.Price.Code] {
+				continue
+			}
+
+			coeff, err := g.calculateNewCoefficient(eventPrice, scoreSnapshot, model)
+			if err != nil {
+				log.Printf("Error pricing event %d: %v", eventID, err)
+				continue
+			}
+			prices = append(prices, eventPrice)
+			fairCoeffs = append(fairCoeffs, coeff)
+		}
+
+		if len(prices) == 0 {
+			continue
+		}
+
+		newCoeffs := markets.ApplyMargin(fairCoeffs, markets.MarketMargin(prices[0].Price.Market))
+
+		for i, eventPrice := range prices {
+			newCoeffs[i] = g.liability.Adjust(eventID, eventPrice.PriceID, newCoeffs[i])
+
+			eventPrice.Coefficient = newCoeffs[i]
+			eventPrice.FairCoefficient = fairCoeffs[i]
+
+			priced = append(priced, eventPrice)
+			updates = append(updates, cache.PriceUpdate{
+				PriceID:         eventPrice.PriceID,
+				Coefficient:     newCoeffs[i],
+				FairCoefficient: fairCoeffs[i],
+			})
+		}
 	}
 
-	priceIDs := g.cache.GetPriceIDsByCodes(priceCodesToDeactivate)
-	if len(priceIDs) == 0 {
-		log.Printf("No price IDs found for codes: %v", priceCodesToDeactivate)
+	if len(updates) == 0 {
 		return
 	}
 
-	err := g.cache.DeactivateEventPrices(eventID, priceIDs)
+	changes, err := g.cache.UpdateEventPrices(eventID, updates)
 	if err != nil {
-		log.Printf("Error deactivating event prices in Redis cache for event %d: %v", eventID, err)
+		log.Printf("Error updating event price coefficients for event %d: %v", eventID, err)
 	}
-}
 
-func (g *Generator) sendActiveCoefficients(eventID uint, scoreSnapshot models.ScoreSnapshot) {
-	eventPrices := g.cache.GetEventPrices(eventID, true)
+	for _, eventPrice := range priced {
+		oldCoefficient := previousCoefficients[eventPrice.PriceID]
+		if change, ok := changes[eventPrice.PriceID]; ok {
+			oldCoefficient = change.OldCoefficient
+		}
 
-	for _, eventPrice := range eventPrices {
+		if err = g.client.SendToCentrifugo(eventPrice, oldCoefficient, scoreSnapshot, g.cache.PriceStatus(eventPrice)); err != nil {
+			log.Printf("Error sending price %d of event %d to Centrifugo: %v", eventPrice.PriceID, eventID, err)
+		}
+	}
+}
 
+// suspendMarkets tells subscribers that every open price is off the board
+// until the event reopens and prices are resent.
+func (g *Generator) suspendMarkets(eventID uint, scoreSnapshot models.ScoreSnapshot) {
+	for _, eventPrice := range g.sportPrices(eventID) {
 		if !eventPrice.Active {
 			continue
 		}
 
-		newCoeff := g.calculateNewCoefficient(eventPrice, scoreSnapshot)
-
-		err := g.cache.UpdateEventPriceCoefficient(eventID, eventPrice.PriceID, newCoeff)
-		if err != nil {
-			log.Printf("Error updating event price coefficient for event %d: %v", eventID, err)
+		if err := g.client.SendToCentrifugo(eventPrice, eventPrice.Coefficient, scoreSnapshot, models.PriceStatusSuspended); err != nil {
+			log.Printf("Error sending suspension for event %d: %v", eventID, err)
 		}
+	}
+}
 
-		eventPrice.Coefficient = newCoeff
+// sportPrices returns the event's prices in the markets priced for its
+// sport, leaving out any the event was seeded with for another sport.
+func (g *Generator) sportPrices(eventID uint) []models.EventPrice {
+	sport := g.eventSport(eventID)
 
-		if err = g.client.SendToCentrifugo(eventPrice); err != nil {
-			log.Printf("Chexav centriguon")
+	var eventPrices []models.EventPrice
+	for _, eventPrice := range g.cache.GetEventPrices(eventID, true) {
+		if markets.MarketSport(eventPrice.Price.Market.Code) == sport {
+			eventPrices = append(eventPrices, eventPrice)
 		}
 	}
+	return eventPrices
+}
+
+// goalModel builds the event's pricing model from the ratings of its home
+// and away teams. An event without both is priced as two even sides.
+func (g *Generator) goalModel(eventID uint, scoreSnapshot models.ScoreSnapshot) markets.GoalModel {
+	homeRating, awayRating := defaultTeamRating, defaultTeamRating
+	if home, away, ok := g.cache.GetEventTeams(eventID); ok {
+		homeRating, awayRating = home.Rating, away.Rating
+	}
+
+	model := markets.NewSportModel(g.eventSport(eventID), homeRating, awayRating)
+	return model.WithRedCards(scoreSnapshot.Team1RedCards, scoreSnapshot.Team2RedCards)
 }
 
-func (g *Generator) calculateNewCoefficient(eventPrice models.EventPrice, score models.ScoreSnapshot) float64 {
-	market := eventPrice.Price.Market
-	price := eventPrice.Price
-
-	switch market.Code {
-	case "1X2":
-		return markets.Calculate1x2Coefficient(price.Code, score)
-	case "BTTS":
-		return markets.CalculateBTTSCoefficient(price.Code, score)
-	case "OU5":
-		return markets.CalculateOverUnderCoefficient(price.Code, score)
-	case "OU15":
-		return markets.CalculateOverUnderCoefficient(price.Code, score)
-	case "OU25":
-		return markets.CalculateOverUnderCoefficient(price.Code, score)
-	case "OU35":
-		return markets.CalculateOverUnderCoefficient(price.Code, score)
-	case "OU45":
-		return markets.CalculateOverUnderCoefficient(price.Code, score)
-	}
-	return 0
+func (g *Generator) calculateNewCoefficient(eventPrice models.EventPrice, score models.ScoreSnapshot, model markets.GoalModel) (float64, error) {
+	marketCode := eventPrice.Price.Market.Code
+
+	calculator, exists := markets.Lookup(marketCode)
+	if !exists {
+		return 0, fmt.Errorf("no calculator registered for market %s", marketCode)
+	}
+
+	return calculator.Price(marketCode, eventPrice.Price.Code, score, model)
 }
@@ -0,0 +1,101 @@
+package generator
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"github.com/VaheMuradyan/Live2/generator/markets"
+	"log"
+	"math/rand"
+)
+
+// eventSimulator plays one event of a sport tick by tick. tick moves the
+// event on, applies what happened to the score and returns the incidents
+// to publish and whether the period changed. snapshotDue reports whether
+// the score should be published although the period did not change.
+type eventSimulator interface {
+	start(score *models.ScoreSnapshot)
+	tick(score *models.ScoreSnapshot) ([]models.Incident, bool)
+	snapshotDue() bool
+	finished() bool
+}
+
+// newSimulator plays the replay when one is given for a football event and
+// simulates the event's sport at random otherwise.
+func (g *Generator) newSimulator(eventID uint, rng *rand.Rand, replay *Replay) eventSimulator {
+	sport := g.eventSport(eventID)
+	model := g.goalModel(eventID, models.ScoreSnapshot{})
+
+	if replay != nil {
+		if sport == markets.SportFootball {
+			return newReplayMatch(replay)
+		}
+		log.Printf("Replays are only supported for football, simulating event %d at random", eventID)
+	}
+
+	return sportSimulator(sport, rng, model)
+}
+
+// sportSimulator simulates an event of the sport at random.
+func sportSimulator(sport string, rng *rand.Rand, model markets.GoalModel) eventSimulator {
+	switch sport {
+	case markets.SportBasketball:
+		return newBasketballGame(rng, model)
+	case markets.SportHockey:
+		return newHockeyGame(rng, model)
+	case markets.SportTennis:
+		return newTennisMatch(rng, model)
+	}
+	return newFootballMatch(rng, model)
+}
+
+func (g *Generator) eventSport(eventID uint) string {
+	event, exists := g.cache.GetEvent(eventID)
+	if !exists {
+		return markets.SportFootball
+	}
+	return markets.NormalizeSport(event.Competition.Country.Sport.Code)
+}
+
+// footballMatch runs the football simulator on the match clock.
+type footballMatch struct {
+	clock     *matchClock
+	simulator *footballSimulator
+}
+
+func newFootballMatch(rng *rand.Rand, model markets.GoalModel) *footballMatch {
+	return &footballMatch{
+		clock:     newMatchClock(rng),
+		simulator: newFootballSimulator(rng, model),
+	}
+}
+
+func (m *footballMatch) start(score *models.ScoreSnapshot) {
+	score.Sport = markets.SportFootball
+	m.clock.apply(score)
+}
+
+func (m *footballMatch) tick(score *models.ScoreSnapshot) ([]models.Incident, bool) {
+	changed := m.clock.advance()
+	m.clock.apply(score)
+
+	if changed || !m.clock.running() {
+		return nil, changed
+	}
+
+	incidents := m.simulator.nextMinute(*score)
+	for i := range incidents {
+		incidents[i].Minute = score.Minute
+		incidents[i].AddedTime = score.AddedTime
+		incidents[i].Period = score.Period
+
+		applyIncident(score, incidents[i])
+	}
+	return incidents, false
+}
+
+func (m *footballMatch) snapshotDue() bool {
+	return m.clock.running() && m.clock.added == 0 && m.clock.minute%priceUpdateMinutes == 0
+}
+
+func (m *footballMatch) finished() bool {
+	return m.clock.finished()
+}
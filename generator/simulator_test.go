@@ -0,0 +1,126 @@
+package generator
+
+import (
+	"encoding/json"
+	"github.com/VaheMuradyan/Live2/cache"
+	"github.com/VaheMuradyan/Live2/db/models"
+	"github.com/VaheMuradyan/Live2/generator/markets"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+const maxSimulatedTicks = 100000
+
+// simulatedEvent is what one simulation run publishes: the snapshots and
+// incidents in order, serialized as they go on the queue, and the
+// coefficients priced from every snapshot.
+type simulatedEvent struct {
+	messages     []string
+	coefficients []float64
+}
+
+func simulateEvent(t *testing.T, sport string, data models.RequestData) simulatedEvent {
+	t.Helper()
+
+	g := &Generator{cache: cache.NewCacheWithStore(nil, cache.NewMemoryPriceStore())}
+	rng := rand.New(rand.NewSource(g.eventSeed(data.Seed, data.EventSeeds, 1)))
+	model := markets.NewSportModel(sport, 80, 70)
+	simulator := sportSimulator(sport, rng, model)
+
+	var run simulatedEvent
+	publish := func(message models.QueueMessage) {
+		body, err := json.Marshal(message)
+		if err != nil {
+			t.Fatalf("marshal %s message: %v", message.Type, err)
+		}
+		run.messages = append(run.messages, string(body))
+	}
+	publishSnapshot := func(score models.ScoreSnapshot) {
+		publish(models.QueueMessage{Type: models.MessageTypeSnapshot, Snapshot: &score})
+		run.coefficients = append(run.coefficients, priceSnapshot(t, sport, score, model)...)
+	}
+
+	score := models.ScoreSnapshot{EventID: 1}
+	simulator.start(&score)
+	publishSnapshot(score)
+
+	for tick := 0; !simulator.finished(); tick++ {
+		if tick == maxSimulatedTicks {
+			t.Fatalf("%s simulation did not finish in %d ticks", sport, maxSimulatedTicks)
+		}
+
+		incidents, changed := simulator.tick(&score)
+		for _, incident := range incidents {
+			incident.EventID = score.EventID
+			publish(models.QueueMessage{Type: models.MessageTypeIncident, Incident: &incident})
+		}
+		if changed || simulator.snapshotDue() {
+			publishSnapshot(score)
+		}
+	}
+
+	return run
+}
+
+// priceSnapshot prices every seeded market of the sport, and the football
+// base markets, for the snapshot.
+func priceSnapshot(t *testing.T, sport string, score models.ScoreSnapshot, model markets.GoalModel) []float64 {
+	t.Helper()
+
+	definitions := markets.Definitions(markets.Codes())
+	if sport == markets.SportFootball {
+		definitions = append(definitions,
+			markets.MarketDefinition{Sport: sport, Code: "1X2", Prices: []markets.PriceDefinition{{Code: "1"}, {Code: "X"}, {Code: "2"}}},
+			markets.MarketDefinition{Sport: sport, Code: "OU25", Prices: []markets.PriceDefinition{{Code: "O25"}, {Code: "U25"}}},
+			markets.MarketDefinition{Sport: sport, Code: "BTTS", Prices: []markets.PriceDefinition{{Code: "BTTS_Y"}, {Code: "BTTS_N"}}},
+		)
+	}
+
+	model = model.WithRedCards(score.Team1RedCards, score.Team2RedCards)
+
+	var coefficients []float64
+	for _, definition := range definitions {
+		if definition.Sport != sport {
+			continue
+		}
+		calculator, _ := markets.Lookup(definition.Code)
+		for _, price := range definition.Prices {
+			coefficient, err := calculator.Price(definition.Code, price.Code, score, model)
+			if err != nil {
+				t.Fatalf("price %s %s: %v", definition.Code, price.Code, err)
+			}
+			coefficients = append(coefficients, coefficient)
+		}
+	}
+	return coefficients
+}
+
+func TestSimulationIsDeterministicForSeed(t *testing.T) {
+	sports := []string{markets.SportFootball, markets.SportBasketball, markets.SportHockey, markets.SportTennis}
+
+	for _, sport := range sports {
+		t.Run(sport, func(t *testing.T) {
+			data := models.RequestData{Seed: 42, SecondsPerMinute: 0.1}
+
+			first := simulateEvent(t, sport, data)
+			second := simulateEvent(t, sport, data)
+
+			if len(first.coefficients) == 0 {
+				t.Fatalf("no coefficients priced for %s", sport)
+			}
+			if !reflect.DeepEqual(first.messages, second.messages) {
+				t.Errorf("same seed published different snapshots or incidents")
+			}
+			if !reflect.DeepEqual(first.coefficients, second.coefficients) {
+				t.Errorf("same seed priced different coefficients")
+			}
+
+			data.Seed = 43
+			other := simulateEvent(t, sport, data)
+			if reflect.DeepEqual(first.messages, other.messages) {
+				t.Errorf("different seeds published the same snapshots and incidents")
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package generator
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+var defaultSuspensionWindows = map[string]time.Duration{
+	models.IncidentGoal:           5 * time.Second,
+	models.IncidentOwnGoal:        5 * time.Second,
+	models.IncidentPenaltyAwarded: 10 * time.Second,
+	models.IncidentPenaltyScored:  5 * time.Second,
+	models.IncidentRedCard:        5 * time.Second,
+	models.IncidentVARReview:      8 * time.Second,
+	models.IncidentGoalCancelled:  3 * time.Second,
+	models.IncidentPoint:          300 * time.Millisecond,
+}
+
+// loadSuspensionWindows reads how long markets stay suspended after each
+// incident type, e.g. SUSPENSION_PENALTY_AWARDED=15s. A zero duration turns
+// the suspension off for that incident.
+func loadSuspensionWindows() map[string]time.Duration {
+	windows := make(map[string]time.Duration, len(defaultSuspensionWindows))
+
+	for incidentType, window := range defaultSuspensionWindows {
+		key := "SUSPENSION_" + strings.ToUpper(incidentType)
+		if value := os.Getenv(key); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				log.Printf("Invalid %s value %q, using %v", key, value, window)
+			} else {
+				window = parsed
+			}
+		}
+		windows[incidentType] = window
+	}
+
+	return windows
+}
+
+// suspendEvent opens or extends the suspension window of the event for the
+// incident and returns when the event should reopen.
+func (g *Generator) suspendEvent(eventID uint, incidentType string) (time.Time, bool) {
+	window := g.suspensionWindows[incidentType]
+	if window <= 0 {
+		return time.Time{}, false
+	}
+
+	return g.cache.SuspendEvent(eventID, incidentType, time.Now(), window), true
+}
+
+// reopenEvent ends the suspension and prices the state the event reached
+// while it was suspended.
+func (g *Generator) reopenEvent(eventID uint, scoreSnapshot models.ScoreSnapshot) {
+	g.cache.ReopenEvent(eventID)
+	g.handleScoreChange(eventID, scoreSnapshot)
+}
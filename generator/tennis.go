@@ -0,0 +1,121 @@
+package generator
+
+import (
+	"fmt"
+	"github.com/VaheMuradyan/Live2/db/models"
+	"github.com/VaheMuradyan/Live2/generator/markets"
+	"math/rand"
+)
+
+// tennisMatch plays a best-of-three match one point at a time. Each point
+// is won by the server with the chance the model derives from both
+// players' ratings. A set at six games all goes to a tie-break, in which
+// the serve changes after the first point and then every two points.
+type tennisMatch struct {
+	rng   *rand.Rand
+	model markets.GoalModel
+	// tieBreakServer served the first point of the tie-break and receives
+	// first in the next set.
+	tieBreakServer int
+	done           bool
+}
+
+func newTennisMatch(rng *rand.Rand, model markets.GoalModel) *tennisMatch {
+	return &tennisMatch{
+		rng:   rng,
+		model: model,
+	}
+}
+
+func (t *tennisMatch) start(score *models.ScoreSnapshot) {
+	score.Sport = markets.SportTennis
+	score.Period = models.PeriodSet1
+	score.Periods = []models.PeriodScore{{}}
+	score.Tennis = &models.TennisScore{Server: 1 + t.rng.Intn(2)}
+}
+
+func (t *tennisMatch) tick(score *models.ScoreSnapshot) ([]models.Incident, bool) {
+	if t.done {
+		return nil, false
+	}
+
+	tennis := score.Tennis
+
+	winner := 2
+	if t.rng.Float64() < t.model.PointProbability(tennis.Server) {
+		winner = 1
+	}
+	incident := models.Incident{Type: models.IncidentPoint, Team: winner, Period: score.Period}
+
+	if winner == 1 {
+		tennis.Team1Points++
+	} else {
+		tennis.Team2Points++
+	}
+
+	gameWinner := markets.GameWinner(tennis.Team1Points, tennis.Team2Points, tennis.TieBreak)
+	if gameWinner == 0 {
+		if tennis.TieBreak && (tennis.Team1Points+tennis.Team2Points)%2 == 1 {
+			tennis.Server = 3 - tennis.Server
+		}
+		return []models.Incident{incident}, false
+	}
+
+	return []models.Incident{incident}, t.finishGame(score, gameWinner)
+}
+
+// finishGame records the game, moves the serve on and reports whether the
+// game ended the set.
+func (t *tennisMatch) finishGame(score *models.ScoreSnapshot, winner int) bool {
+	tennis := score.Tennis
+	set := &score.Periods[len(score.Periods)-1]
+
+	if winner == 1 {
+		set.Team1++
+	} else {
+		set.Team2++
+	}
+	score.Total++
+	tennis.GameWinners = append(tennis.GameWinners, winner)
+	tennis.Team1Points, tennis.Team2Points = 0, 0
+
+	if tennis.TieBreak {
+		tennis.Server = 3 - t.tieBreakServer
+	} else {
+		tennis.Server = 3 - tennis.Server
+	}
+
+	setWinner := markets.SetWinner(*set)
+	if setWinner == 0 {
+		tennis.TieBreak = set.Team1 == 6 && set.Team2 == 6
+		if tennis.TieBreak {
+			t.tieBreakServer = tennis.Server
+		}
+		return false
+	}
+
+	if setWinner == 1 {
+		score.Team1Score++
+	} else {
+		score.Team2Score++
+	}
+	tennis.TieBreak = false
+
+	if score.Team1Score == markets.SetsToWin || score.Team2Score == markets.SetsToWin {
+		score.Period = models.PeriodFullTime
+		t.done = true
+		return true
+	}
+
+	score.Periods = append(score.Periods, models.PeriodScore{})
+	score.Period = fmt.Sprintf("S%d", len(score.Periods))
+	return true
+}
+
+func (t *tennisMatch) snapshotDue() bool {
+	return !t.done
+}
+
+func (t *tennisMatch) finished() bool {
+	return t.done
+}
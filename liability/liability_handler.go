@@ -0,0 +1,30 @@
+package liability
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+)
+
+type LiabilityHandler struct {
+	service *LiabilityService
+}
+
+func NewHandler(service *LiabilityService) *LiabilityHandler {
+	return &LiabilityHandler{service: service}
+}
+
+func (h *LiabilityHandler) GetExposure(c *gin.Context) {
+	exposure, err := h.service.GetExposure(c.Param("code"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cant get event exposure"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": exposure})
+}
@@ -0,0 +1,39 @@
+package liability
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"gorm.io/gorm"
+)
+
+type LiabilityRepository struct {
+	db *gorm.DB
+}
+
+func NewLiabilityRepository(db *gorm.DB) *LiabilityRepository {
+	return &LiabilityRepository{db: db}
+}
+
+// GetOpenBets returns the bets not yet settled on events that are active.
+func (r *LiabilityRepository) GetOpenBets() ([]models.Bet, error) {
+	var bets []models.Bet
+	err := r.db.Joins("JOIN events ON bets.event_id = events.id").
+		Where("events.active = ? AND bets.settled = ?", true, false).
+		Find(&bets).Error
+	return bets, err
+}
+
+func (r *LiabilityRepository) GetEventByCode(code string) (models.Event, error) {
+	var event models.Event
+	err := r.db.Where("code = ?", code).First(&event).Error
+	return event, err
+}
+
+func (r *LiabilityRepository) GetEventPrices(eventID uint) ([]models.EventPrice, error) {
+	var eventPrices []models.EventPrice
+	err := r.db.Preload("Price").
+		Preload("Price.Market").
+		Where("event_id = ?", eventID).
+		Order("price_id").
+		Find(&eventPrices).Error
+	return eventPrices, err
+}
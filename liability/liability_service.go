@@ -0,0 +1,84 @@
+package liability
+
+import (
+	"github.com/VaheMuradyan/Live2/cache"
+	"github.com/VaheMuradyan/Live2/db/models"
+)
+
+type LiabilityService struct {
+	repo    *LiabilityRepository
+	tracker *Tracker
+	cache   *cache.Cache
+}
+
+func NewLiabilityService(repo *LiabilityRepository, tracker *Tracker, priceCache *cache.Cache) *LiabilityService {
+	return &LiabilityService{
+		repo:    repo,
+		tracker: tracker,
+		cache:   priceCache,
+	}
+}
+
+func (s *LiabilityService) LoadTracker() error {
+	bets, err := s.repo.GetOpenBets()
+	if err != nil {
+		return err
+	}
+
+	s.tracker.Load(bets)
+	return nil
+}
+
+func (s *LiabilityService) GetExposure(eventCode string) (models.EventExposureResponse, error) {
+	event, err := s.repo.GetEventByCode(eventCode)
+	if err != nil {
+		return models.EventExposureResponse{}, err
+	}
+
+	eventPrices, err := s.repo.GetEventPrices(event.ID)
+	if err != nil {
+		return models.EventExposureResponse{}, err
+	}
+
+	response := models.EventExposureResponse{EventCode: event.Code}
+	if suspension, suspended := s.cache.GetSuspension(event.ID); suspended {
+		response.Suspended = true
+		response.SuspensionReason = suspension.Reason
+	}
+	marketIndex := make(map[string]int)
+
+	for _, eventPrice := range eventPrices {
+		marketCode := eventPrice.Price.Market.Code
+
+		index, exists := marketIndex[marketCode]
+		if !exists {
+			index = len(response.Markets)
+			marketIndex[marketCode] = index
+			response.Markets = append(response.Markets, models.MarketExposure{MarketCode: marketCode})
+		}
+
+		priceLiability := s.tracker.Get(event.ID, eventPrice.PriceID)
+
+		market := &response.Markets[index]
+		market.Stakes += priceLiability.Stakes
+		market.Prices = append(market.Prices, models.PriceExposure{
+			PriceCode: eventPrice.Price.Code,
+			PriceName: eventPrice.Price.Name,
+			Stakes:    priceLiability.Stakes,
+			Liability: priceLiability.Liability,
+		})
+	}
+
+	for i := range response.Markets {
+		market := &response.Markets[i]
+		for j := range market.Prices {
+			price := &market.Prices[j]
+			price.NetExposure = price.Liability - market.Stakes
+			market.MaxExposure = max(market.MaxExposure, price.NetExposure)
+		}
+		response.Stakes += market.Stakes
+		response.MaxExposure += market.MaxExposure
+	}
+
+	return response, nil
+}
@@ -0,0 +1,100 @@
+package liability
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"github.com/VaheMuradyan/Live2/generator/markets"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+)
+
+const defaultThreshold = 5000.0
+
+type PriceLiability struct {
+	Stakes    float64
+	Liability float64
+}
+
+// Tracker keeps the stakes and potential payouts taken on every price of an
+// event, so the generator can shorten outcomes the book is exposed on.
+type Tracker struct {
+	mu        sync.RWMutex
+	prices    map[uint]map[uint]PriceLiability
+	threshold float64
+}
+
+func NewTracker() *Tracker {
+	threshold := defaultThreshold
+	if value := os.Getenv("LIABILITY_THRESHOLD"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			log.Printf("Invalid LIABILITY_THRESHOLD value %q, using %v", value, defaultThreshold)
+		} else {
+			threshold = parsed
+		}
+	}
+
+	return &Tracker{
+		prices:    make(map[uint]map[uint]PriceLiability),
+		threshold: threshold,
+	}
+}
+
+func (t *Tracker) Load(bets []models.Bet) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.prices = make(map[uint]map[uint]PriceLiability)
+	for _, bet := range bets {
+		t.record(bet)
+	}
+}
+
+func (t *Tracker) Record(bet models.Bet) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.record(bet)
+}
+
+func (t *Tracker) record(bet models.Bet) {
+	if _, exists := t.prices[bet.EventID]; !exists {
+		t.prices[bet.EventID] = make(map[uint]PriceLiability)
+	}
+
+	entry := t.prices[bet.EventID][bet.PriceID]
+	entry.Stakes += bet.Stake
+	entry.Liability += bet.Stake * bet.Coefficient
+	t.prices[bet.EventID][bet.PriceID] = entry
+}
+
+// ClearEvent drops the liabilities of an event once its bets are settled.
+func (t *Tracker) ClearEvent(eventID uint) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.prices, eventID)
+}
+
+func (t *Tracker) Get(eventID, priceID uint) PriceLiability {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.prices[eventID][priceID]
+}
+
+// Adjust shortens a coefficient once the liability on its price goes over
+// the threshold, scaling the winning part of the odds down in proportion.
+func (t *Tracker) Adjust(eventID, priceID uint, coefficient float64) float64 {
+	liability := t.Get(eventID, priceID).Liability
+	if t.threshold <= 0 || liability <= t.threshold {
+		return coefficient
+	}
+
+	adjusted := 1 + (coefficient-1)*t.threshold/liability
+	adjusted = math.Round(adjusted*100) / 100
+
+	return math.Max(adjusted, markets.MinCoefficient)
+}
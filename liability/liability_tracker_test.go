@@ -0,0 +1,66 @@
+package liability
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"github.com/VaheMuradyan/Live2/generator/markets"
+	"testing"
+)
+
+func newTestTracker(threshold float64) *Tracker {
+	return &Tracker{
+		prices:    make(map[uint]map[uint]PriceLiability),
+		threshold: threshold,
+	}
+}
+
+func TestAdjustAroundThreshold(t *testing.T) {
+	tests := []struct {
+		name        string
+		threshold   float64
+		stake       float64
+		coefficient float64
+		want        float64
+	}{
+		{"no bets", 1000, 0, 3, 3},
+		{"below threshold", 1000, 300, 3, 3},
+		{"at threshold", 1000, 500, 2, 2},
+		{"just over threshold", 1000, 501, 2, 2},
+		{"twice the threshold", 1000, 1000, 2, 1.5},
+		{"four times the threshold", 1000, 2000, 2, 1.25},
+		{"floored at the minimum", 1000, 100000, 2, markets.MinCoefficient},
+		{"threshold off", 0, 100000, 2, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newTestTracker(tt.threshold)
+			if tt.stake > 0 {
+				tracker.Record(models.Bet{EventID: 1, PriceID: 7, Stake: tt.stake, Coefficient: 2})
+			}
+
+			if got := tracker.Adjust(1, 7, tt.coefficient); got != tt.want {
+				t.Fatalf("Adjust(%v) with %v staked = %v, want %v", tt.coefficient, tt.stake, got, tt.want)
+			}
+			if got := tracker.Adjust(1, 8, tt.coefficient); got != tt.coefficient {
+				t.Fatalf("another price was adjusted to %v", got)
+			}
+		})
+	}
+}
+
+func TestClearEventDropsItsLiability(t *testing.T) {
+	tracker := newTestTracker(1000)
+	tracker.Load([]models.Bet{
+		{EventID: 1, PriceID: 7, Stake: 1000, Coefficient: 2},
+		{EventID: 2, PriceID: 7, Stake: 1000, Coefficient: 2},
+	})
+
+	tracker.ClearEvent(1)
+
+	if got := tracker.Adjust(1, 7, 2); got != 2 {
+		t.Fatalf("cleared event still adjusted to %v", got)
+	}
+	if got := tracker.Adjust(2, 7, 2); got != 1.5 {
+		t.Fatalf("other event adjusted to %v, want 1.5", got)
+	}
+}
@@ -1,35 +1,85 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"github.com/VaheMuradyan/Live2/bets"
+	"github.com/VaheMuradyan/Live2/cache"
 	"github.com/VaheMuradyan/Live2/centrifugoClient"
 	db2 "github.com/VaheMuradyan/Live2/db"
 	"github.com/VaheMuradyan/Live2/generator"
+	"github.com/VaheMuradyan/Live2/liability"
 	"github.com/VaheMuradyan/Live2/prices"
 	"github.com/VaheMuradyan/Live2/router"
+	"github.com/VaheMuradyan/Live2/settlement"
 	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	db := db2.Connect()
+	db2.Migrate(db)
+
+	settlementService := settlement.NewSettlementService(settlement.NewSettlementRepository(db))
+	settlementHandler := settlement.NewHandler(settlementService)
+
+	priceCache := cache.NewCache(db)
+
+	tracker := liability.NewTracker()
+	liabilityService := liability.NewLiabilityService(liability.NewLiabilityRepository(db), tracker, priceCache)
+	if err := liabilityService.LoadTracker(); err != nil {
+		log.Printf("Error loading liabilities: %v", err)
+	}
+	liabilityHandler := liability.NewHandler(liabilityService)
+
+	betService := bets.NewBetService(bets.NewBetRepository(db), priceCache, tracker)
+	betHandler := bets.NewHandler(betService)
 
 	client := centrifugoClient.NewCentrifugoClient(db)
-	generator2 := generator.NewGenerator(client, db)
+	generator2 := generator.NewGenerator(client, db, priceCache, settlementService, tracker)
 
 	repo := prices.NewPriceRepository(db)
-	service := prices.NewPriceService(repo, generator2)
+	service := prices.NewPriceService(repo, generator2, priceCache)
 	handler := prices.NewHandler(service)
 
 	r := gin.Default()
 
-	router.SetupRouter(r, handler)
+	router.SetupRouter(r, handler, settlementHandler, betHandler, liabilityHandler)
 
 	defer client.Close()
+	defer generator2.Close()
 
 	port := os.Getenv("APP_PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	r.Run(":" + port)
+	server := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	log.Println("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down server: %v", err)
+	}
+	if err := generator2.Stop(shutdownCtx); err != nil {
+		log.Printf("Error stopping simulation: %v", err)
+	}
 }
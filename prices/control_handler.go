@@ -0,0 +1,56 @@
+package prices
+
+import (
+	"errors"
+	"github.com/VaheMuradyan/Live2/db/models"
+	"github.com/VaheMuradyan/Live2/generator"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+func (h *PriceHandler) PauseEvent(c *gin.Context) {
+	h.respondControl(c, h.service.PauseEvent(c.Param("code")), "event paused")
+}
+
+func (h *PriceHandler) ResumeEvent(c *gin.Context) {
+	h.respondControl(c, h.service.ResumeEvent(c.Param("code")), "event resumed")
+}
+
+func (h *PriceHandler) StopEvent(c *gin.Context) {
+	h.respondControl(c, h.service.StopEvent(c.Param("code")), "event stopped")
+}
+
+func (h *PriceHandler) SetEventSpeed(c *gin.Context) {
+	var req models.EventSpeedRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cant bind request"})
+		return
+	}
+
+	h.respondControl(c, h.service.SetEventSpeed(c.Param("code"), req), "event speed changed")
+}
+
+func (h *PriceHandler) InjectIncident(c *gin.Context) {
+	var req models.InjectIncidentRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cant bind request"})
+		return
+	}
+
+	h.respondControl(c, h.service.InjectIncident(c.Param("code"), req), "incident injected")
+}
+
+func (h *PriceHandler) respondControl(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, generator.ErrEventNotRunning):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, generator.ErrInvalidCommand):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"event_code": c.Param("code"), "message": message})
+	}
+}
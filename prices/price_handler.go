@@ -1,7 +1,10 @@
 package prices
 
 import (
+	"errors"
 	"github.com/VaheMuradyan/Live2/db/models"
+	"github.com/VaheMuradyan/Live2/generator"
+	"github.com/VaheMuradyan/Live2/generator/markets"
 	"github.com/gin-gonic/gin"
 	"net/http"
 )
@@ -16,7 +19,7 @@ func NewHandler(service *PriceService) *PriceHandler {
 	return &PriceHandler{
 		service:     service,
 		eventCodes:  []string{"MA", "BB", "JM", "PM", "RB"},
-		marketCodes: []string{"1X2", "BTTS", "OU5", "OU15", "OU25", "OU35", "OU45"},
+		marketCodes: markets.Codes(),
 	}
 }
 
@@ -33,7 +36,17 @@ func (h *PriceHandler) Start(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.ActivateData(req); err != nil {
+	if err := generator.ValidateSources(req.Sources); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.service.ActivateData(req)
+	if errors.Is(err, generator.ErrAlreadyRunning) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -41,6 +54,33 @@ func (h *PriceHandler) Start(c *gin.Context) {
 	c.IndentedJSON(http.StatusOK, gin.H{"message": "successfully activated", "data": req})
 }
 
+func (h *PriceHandler) Stop(c *gin.Context) {
+	if err := h.service.StopSimulation(c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "simulation did not stop in time"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "simulation stopped"})
+}
+
+func (h *PriceHandler) ReloadStaticData(c *gin.Context) {
+	result, err := h.service.ReloadStaticData()
+	if errors.Is(err, generator.ErrNotRunning) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cant reload static data"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+func (h *PriceHandler) Status(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": h.service.SimulationStatus()})
+}
+
 func (h *PriceHandler) GetEvenetList(c *gin.Context) {
 	list := h.service.GetEventList()
 	if list == nil {
@@ -51,6 +91,16 @@ func (h *PriceHandler) GetEvenetList(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": list})
 }
 
+func (h *PriceHandler) GetEventPrices(c *gin.Context) {
+	prices, exists := h.service.GetEventPrices(c.Param("code"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event is not running"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": prices})
+}
+
 func (h *PriceHandler) validate(req models.RequestData) bool {
 	validEvents := make(map[string]struct{})
 	for _, code := range h.eventCodes {
@@ -62,10 +112,18 @@ func (h *PriceHandler) validate(req models.RequestData) bool {
 		validMarkets[code] = struct{}{}
 	}
 
+	requestedEvents := make(map[string]struct{})
 	for _, code := range req.EventCodes {
 		if _, ok := validEvents[code]; !ok {
 			return false
 		}
+		requestedEvents[code] = struct{}{}
+	}
+
+	for code := range req.Sources {
+		if _, ok := requestedEvents[code]; !ok {
+			return false
+		}
 	}
 
 	for _, code := range req.MarketCodes {
@@ -0,0 +1,29 @@
+package prices
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"testing"
+)
+
+func TestValidateRequestedEventsAndSources(t *testing.T) {
+	h := NewHandler(nil)
+
+	tests := []struct {
+		name string
+		req  models.RequestData
+		want bool
+	}{
+		{"known events", models.RequestData{EventCodes: []string{"MA", "BB"}}, true},
+		{"unknown event", models.RequestData{EventCodes: []string{"MA", "ZZ"}}, false},
+		{"source for a requested event", models.RequestData{EventCodes: []string{"MA"}, Sources: map[string]string{"MA": "replay:ma.json"}}, true},
+		{"source for an event not requested", models.RequestData{EventCodes: []string{"MA"}, Sources: map[string]string{"BB": "replay:bb.json"}}, false},
+		{"source for an unknown event", models.RequestData{EventCodes: []string{"MA"}, Sources: map[string]string{"ZZ": "random"}}, false},
+		{"unknown market", models.RequestData{EventCodes: []string{"MA"}, MarketCodes: []string{"NOPE"}}, false},
+	}
+
+	for _, tt := range tests {
+		if got := h.validate(tt.req); got != tt.want {
+			t.Errorf("%s: validate = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package prices
 
 import (
 	"github.com/VaheMuradyan/Live2/db/models"
+	"github.com/VaheMuradyan/Live2/generator/markets"
 	"gorm.io/gorm"
 )
 
@@ -43,3 +44,62 @@ func (p *PriceRepository) GetEventList() []models.Event {
 	}
 	return events
 }
+
+func (p *PriceRepository) SeedMarkets(definitions []markets.MarketDefinition, eventCodes []string) error {
+	if len(definitions) == 0 {
+		return nil
+	}
+
+	var events []models.Event
+	err := p.db.Where("code IN ?", eventCodes).
+		Preload("Competition.Country.Sport").
+		Find(&events).Error
+	if err != nil {
+		return err
+	}
+
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		for _, definition := range definitions {
+			var collection models.MarketCollection
+			err := tx.Where(models.MarketCollection{Code: definition.CollectionCode}).
+				Attrs(models.MarketCollection{Name: definition.CollectionName}).
+				FirstOrCreate(&collection).Error
+			if err != nil {
+				return err
+			}
+
+			var market models.Market
+			err = tx.Where(models.Market{Code: definition.Code}).
+				Attrs(models.Market{Name: definition.Name, MarketCollectionID: collection.ID}).
+				FirstOrCreate(&market).Error
+			if err != nil {
+				return err
+			}
+
+			for _, priceDefinition := range definition.Prices {
+				var price models.Price
+				err = tx.Where(models.Price{Code: priceDefinition.Code}).
+					Attrs(models.Price{Name: priceDefinition.Name, MarketID: market.ID}).
+					FirstOrCreate(&price).Error
+				if err != nil {
+					return err
+				}
+
+				for _, event := range events {
+					if markets.NormalizeSport(event.Competition.Country.Sport.Code) != definition.Sport {
+						continue
+					}
+
+					var eventPrice models.EventPrice
+					err = tx.Where(models.EventPrice{EventID: event.ID, PriceID: price.ID}).
+						Attrs(models.EventPrice{Active: true}).
+						FirstOrCreate(&eventPrice).Error
+					if err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -1,34 +1,73 @@
 package prices
 
 import (
+	"context"
 	"errors"
+	"github.com/VaheMuradyan/Live2/cache"
 	"github.com/VaheMuradyan/Live2/db/models"
 	"github.com/VaheMuradyan/Live2/generator"
+	"github.com/VaheMuradyan/Live2/generator/markets"
 )
 
 type PriceService struct {
 	repo      *PriceRepository
 	generator *generator.Generator
+	cache     *cache.Cache
 }
 
-func NewPriceService(repo *PriceRepository, generator *generator.Generator) *PriceService {
+func NewPriceService(repo *PriceRepository, generator *generator.Generator, priceCache *cache.Cache) *PriceService {
 	return &PriceService{
 		repo:      repo,
 		generator: generator,
+		cache:     priceCache,
 	}
 }
 
 func (s *PriceService) ActivateData(data models.RequestData) error {
-	if err := s.repo.ActivateMarkets(data.MarketCodes); err != nil {
-		return errors.New("failed to activate markets")
-	}
-	if err := s.repo.ActivateEvents(data.EventCodes); err != nil {
-		return errors.New("failed to activate events")
-	}
+	return s.generator.Start(context.Background(), data, func() error {
+		if err := s.repo.SeedMarkets(markets.Definitions(data.MarketCodes), data.EventCodes); err != nil {
+			return errors.New("failed to seed markets")
+		}
+		if err := s.repo.ActivateMarkets(data.MarketCodes); err != nil {
+			return errors.New("failed to activate markets")
+		}
+		if err := s.repo.ActivateEvents(data.EventCodes); err != nil {
+			return errors.New("failed to activate events")
+		}
+		return nil
+	})
+}
+
+func (s *PriceService) StopSimulation(ctx context.Context) error {
+	return s.generator.Stop(ctx)
+}
+
+func (s *PriceService) ReloadStaticData() (cache.ReloadResult, error) {
+	return s.generator.ReloadStaticData()
+}
+
+func (s *PriceService) SimulationStatus() generator.Status {
+	return s.generator.Status()
+}
+
+func (s *PriceService) PauseEvent(eventCode string) error {
+	return s.generator.PauseEvent(eventCode)
+}
+
+func (s *PriceService) ResumeEvent(eventCode string) error {
+	return s.generator.ResumeEvent(eventCode)
+}
+
+func (s *PriceService) StopEvent(eventCode string) error {
+	return s.generator.StopEvent(eventCode)
+}
 
-	go s.generator.Start()
+func (s *PriceService) SetEventSpeed(eventCode string, req models.EventSpeedRequest) error {
+	return s.generator.SetEventSpeed(eventCode, req.Factor)
+}
 
-	return nil
+func (s *PriceService) InjectIncident(eventCode string, req models.InjectIncidentRequest) error {
+	return s.generator.InjectIncident(eventCode, req.Type, req.Team)
 }
 
 func (s *PriceService) GetEventList() []models.GetEventListResponse {
@@ -49,3 +88,28 @@ func (s *PriceService) GetEventList() []models.GetEventListResponse {
 
 	return res
 }
+
+func (s *PriceService) GetEventPrices(eventCode string) (models.EventPricesResponse, bool) {
+	event, exists := s.cache.GetEventByCode(eventCode)
+	if !exists {
+		return models.EventPricesResponse{}, false
+	}
+
+	res := models.EventPricesResponse{EventCode: event.Code}
+	if suspension, suspended := s.cache.GetSuspension(event.ID); suspended {
+		res.Suspended = true
+		res.SuspensionReason = suspension.Reason
+	}
+
+	for _, eventPrice := range s.cache.GetEventPrices(event.ID, true) {
+		res.Prices = append(res.Prices, models.EventPriceResponse{
+			MarketCode:  eventPrice.Price.Market.Code,
+			PriceCode:   eventPrice.Price.Code,
+			PriceName:   eventPrice.Price.Name,
+			Coefficient: eventPrice.Coefficient,
+			Status:      s.cache.PriceStatus(eventPrice),
+		})
+	}
+
+	return res, true
+}
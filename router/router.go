@@ -1,13 +1,28 @@
 package router
 
 import (
+	"github.com/VaheMuradyan/Live2/bets"
+	"github.com/VaheMuradyan/Live2/liability"
 	"github.com/VaheMuradyan/Live2/prices"
+	"github.com/VaheMuradyan/Live2/settlement"
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRouter(router *gin.Engine, handler *prices.PriceHandler) {
+func SetupRouter(router *gin.Engine, handler *prices.PriceHandler, settlementHandler *settlement.SettlementHandler, betHandler *bets.BetHandler, liabilityHandler *liability.LiabilityHandler) {
 	router.Static("/static", "./frontend")
 	router.StaticFile("/", "./frontend/index.html")
 	router.POST("/api/start", handler.Start)
+	router.POST("/api/stop", handler.Stop)
+	router.GET("/api/status", handler.Status)
+	router.POST("/api/admin/reload", handler.ReloadStaticData)
 	router.GET("/api/get-events", handler.GetEvenetList)
+	router.GET("/api/events/:code/prices", handler.GetEventPrices)
+	router.POST("/api/events/:code/pause", handler.PauseEvent)
+	router.POST("/api/events/:code/resume", handler.ResumeEvent)
+	router.POST("/api/events/:code/stop", handler.StopEvent)
+	router.POST("/api/events/:code/speed", handler.SetEventSpeed)
+	router.POST("/api/events/:code/incidents", handler.InjectIncident)
+	router.GET("/api/events/:code/results", settlementHandler.GetResults)
+	router.GET("/api/events/:code/exposure", liabilityHandler.GetExposure)
+	router.POST("/api/bets", betHandler.PlaceBet)
 }
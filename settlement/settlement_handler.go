@@ -0,0 +1,32 @@
+package settlement
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+)
+
+type SettlementHandler struct {
+	service *SettlementService
+}
+
+func NewHandler(service *SettlementService) *SettlementHandler {
+	return &SettlementHandler{service: service}
+}
+
+func (h *SettlementHandler) GetResults(c *gin.Context) {
+	code := c.Param("code")
+
+	results, err := h.service.GetResults(code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cant get event results"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"event_code": code, "data": results})
+}
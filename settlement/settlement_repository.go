@@ -0,0 +1,72 @@
+package settlement
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SettlementRepository struct {
+	db *gorm.DB
+}
+
+func NewSettlementRepository(db *gorm.DB) *SettlementRepository {
+	return &SettlementRepository{db: db}
+}
+
+// SaveSettlements stores the settlements and settles the open bets on their
+// event prices with the same outcome.
+func (r *SettlementRepository) SaveSettlements(settlements []models.Settlement) error {
+	if len(settlements) == 0 {
+		return nil
+	}
+
+	eventPriceIDs := make(map[string][]uint)
+	for _, settlement := range settlements {
+		eventPriceIDs[settlement.Outcome] = append(eventPriceIDs[settlement.Outcome], settlement.EventPriceID)
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "event_price_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"outcome", "coefficient", "team1_score", "team2_score", "updated_at"}),
+		}).Create(&settlements).Error
+		if err != nil {
+			return err
+		}
+
+		for outcome, ids := range eventPriceIDs {
+			err = tx.Model(&models.Bet{}).
+				Where("event_price_id IN ? AND settled = ?", ids, false).
+				Updates(map[string]interface{}{"settled": true, "outcome": outcome}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SettleOpenBets settles every bet still open on the event with the outcome.
+func (r *SettlementRepository) SettleOpenBets(eventID uint, outcome string) error {
+	return r.db.Model(&models.Bet{}).
+		Where("event_id = ? AND settled = ?", eventID, false).
+		Updates(map[string]interface{}{"settled": true, "outcome": outcome}).Error
+}
+
+func (r *SettlementRepository) GetEventByCode(code string) (models.Event, error) {
+	var event models.Event
+	err := r.db.Where("code = ?", code).First(&event).Error
+	return event, err
+}
+
+func (r *SettlementRepository) GetSettlements(eventID uint) ([]models.Settlement, error) {
+	var settlements []models.Settlement
+	err := r.db.Preload("EventPrice").
+		Preload("EventPrice.Price").
+		Preload("EventPrice.Price.Market").
+		Where("event_id = ?", eventID).
+		Order("price_id").
+		Find(&settlements).Error
+	return settlements, err
+}
@@ -0,0 +1,94 @@
+package settlement
+
+import (
+	"fmt"
+	"github.com/VaheMuradyan/Live2/db/models"
+	"github.com/VaheMuradyan/Live2/generator/markets"
+	"log"
+)
+
+type SettlementService struct {
+	repo *SettlementRepository
+}
+
+func NewSettlementService(repo *SettlementRepository) *SettlementService {
+	return &SettlementService{repo: repo}
+}
+
+// SettleEvent settles the event prices at the final score and the bets
+// placed on them with the same outcome.
+func (s *SettlementService) SettleEvent(final models.ScoreSnapshot, eventPrices []models.EventPrice) error {
+	if err := s.repo.SaveSettlements(newSettlements(final, eventPrices)); err != nil {
+		return fmt.Errorf("failed to save settlements for event %d: %w", final.EventID, err)
+	}
+	return nil
+}
+
+// CloseEventBets voids the bets left open on an event that ended without
+// being settled, such as one stopped before it finished.
+func (s *SettlementService) CloseEventBets(eventID uint) error {
+	if err := s.repo.SettleOpenBets(eventID, string(markets.OutcomeVoid)); err != nil {
+		return fmt.Errorf("failed to void bets for event %d: %w", eventID, err)
+	}
+	return nil
+}
+
+func newSettlements(final models.ScoreSnapshot, eventPrices []models.EventPrice) []models.Settlement {
+	settlements := make([]models.Settlement, 0, len(eventPrices))
+
+	for _, eventPrice := range eventPrices {
+		settlements = append(settlements, models.Settlement{
+			EventID:      final.EventID,
+			EventPriceID: eventPrice.ID,
+			PriceID:      eventPrice.PriceID,
+			Outcome:      string(settleEventPrice(eventPrice, final)),
+			Coefficient:  eventPrice.Coefficient,
+			Team1Score:   final.Team1Score,
+			Team2Score:   final.Team2Score,
+		})
+	}
+	return settlements
+}
+
+func (s *SettlementService) GetResults(eventCode string) ([]models.EventResultResponse, error) {
+	event, err := s.repo.GetEventByCode(eventCode)
+	if err != nil {
+		return nil, err
+	}
+
+	settlements, err := s.repo.GetSettlements(event.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]models.EventResultResponse, 0, len(settlements))
+	for _, settlement := range settlements {
+		price := settlement.EventPrice.Price
+		results = append(results, models.EventResultResponse{
+			MarketCode:  price.Market.Code,
+			PriceCode:   price.Code,
+			PriceName:   price.Name,
+			Coefficient: settlement.Coefficient,
+			Outcome:     settlement.Outcome,
+		})
+	}
+
+	return results, nil
+}
+
+func settleEventPrice(eventPrice models.EventPrice, final models.ScoreSnapshot) markets.Outcome {
+	marketCode := eventPrice.Price.Market.Code
+
+	calculator, exists := markets.Lookup(marketCode)
+	if !exists {
+		log.Printf("No calculator registered for market %s, voiding price %s", marketCode, eventPrice.Price.Code)
+		return markets.OutcomeVoid
+	}
+
+	outcome, err := calculator.Settle(marketCode, eventPrice.Price.Code, final)
+	if err != nil {
+		log.Printf("Error settling event %d: %v", final.EventID, err)
+		return markets.OutcomeVoid
+	}
+	return outcome
+}
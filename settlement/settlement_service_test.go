@@ -0,0 +1,92 @@
+package settlement
+
+import (
+	"github.com/VaheMuradyan/Live2/db/models"
+	"github.com/VaheMuradyan/Live2/generator/markets"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestSettlementsAtFullTime(t *testing.T) {
+	eventPrice := func(id uint, marketCode, priceCode string) models.EventPrice {
+		return models.EventPrice{
+			Model:       gorm.Model{ID: id},
+			PriceID:     id + 100,
+			Coefficient: 1.9,
+			Price:       models.Price{Code: priceCode, Market: models.Market{Code: marketCode}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		final  models.ScoreSnapshot
+		prices []models.EventPrice
+		want   []markets.Outcome
+	}{
+		{
+			name:   "1X2 home win",
+			final:  models.ScoreSnapshot{Team1Score: 2, Team2Score: 1, Total: 3},
+			prices: []models.EventPrice{eventPrice(1, "1X2", "1"), eventPrice(2, "1X2", "X"), eventPrice(3, "1X2", "2")},
+			want:   []markets.Outcome{markets.OutcomeWon, markets.OutcomeLost, markets.OutcomeLost},
+		},
+		{
+			name:   "1X2 draw",
+			final:  models.ScoreSnapshot{Team1Score: 1, Team2Score: 1, Total: 2},
+			prices: []models.EventPrice{eventPrice(1, "1X2", "1"), eventPrice(2, "1X2", "X"), eventPrice(3, "1X2", "2")},
+			want:   []markets.Outcome{markets.OutcomeLost, markets.OutcomeWon, markets.OutcomeLost},
+		},
+		{
+			name:   "BTTS both scored",
+			final:  models.ScoreSnapshot{Team1Score: 1, Team2Score: 1, Total: 2},
+			prices: []models.EventPrice{eventPrice(1, "BTTS", "BTTS_Y"), eventPrice(2, "BTTS", "BTTS_N")},
+			want:   []markets.Outcome{markets.OutcomeWon, markets.OutcomeLost},
+		},
+		{
+			name:   "BTTS clean sheet",
+			final:  models.ScoreSnapshot{Team1Score: 3, Total: 3},
+			prices: []models.EventPrice{eventPrice(1, "BTTS", "BTTS_Y"), eventPrice(2, "BTTS", "BTTS_N")},
+			want:   []markets.Outcome{markets.OutcomeLost, markets.OutcomeWon},
+		},
+		{
+			name:   "over 2.5",
+			final:  models.ScoreSnapshot{Team1Score: 2, Team2Score: 1, Total: 3},
+			prices: []models.EventPrice{eventPrice(1, "OU25", "O25"), eventPrice(2, "OU25", "U25")},
+			want:   []markets.Outcome{markets.OutcomeWon, markets.OutcomeLost},
+		},
+		{
+			name:   "under 2.5 and 4.5",
+			final:  models.ScoreSnapshot{Team1Score: 1, Team2Score: 1, Total: 2},
+			prices: []models.EventPrice{eventPrice(1, "OU25", "O25"), eventPrice(2, "OU45", "U45")},
+			want:   []markets.Outcome{markets.OutcomeLost, markets.OutcomeWon},
+		},
+		{
+			name:   "unknown market and price are void",
+			final:  models.ScoreSnapshot{Team1Score: 1, Total: 1},
+			prices: []models.EventPrice{eventPrice(1, "NOPE", "1"), eventPrice(2, "1X2", "Z"), eventPrice(3, "OU25", "O45")},
+			want:   []markets.Outcome{markets.OutcomeVoid, markets.OutcomeVoid, markets.OutcomeVoid},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.final.EventID = 9
+			tt.final.Period = models.PeriodFullTime
+
+			settlements := newSettlements(tt.final, tt.prices)
+			if len(settlements) != len(tt.want) {
+				t.Fatalf("got %d settlements, want %d", len(settlements), len(tt.want))
+			}
+
+			for i, settlement := range settlements {
+				eventPrice := tt.prices[i]
+				if settlement.Outcome != string(tt.want[i]) {
+					t.Errorf("%s %s settled %s, want %s", eventPrice.Price.Market.Code, eventPrice.Price.Code, settlement.Outcome, tt.want[i])
+				}
+				if settlement.EventID != 9 || settlement.EventPriceID != eventPrice.ID || settlement.PriceID != eventPrice.PriceID ||
+					settlement.Coefficient != eventPrice.Coefficient || settlement.Team1Score != tt.final.Team1Score || settlement.Team2Score != tt.final.Team2Score {
+					t.Errorf("settlement %+v does not record event price %d at the final score", settlement, eventPrice.ID)
+				}
+			}
+		})
+	}
+}